@@ -0,0 +1,137 @@
+package buffer
+
+// BlockSelection is a rectangular selection measured in display columns.
+// It spans from the anchor row to the cursor row; CursorCol may lie past the
+// end of short lines so the rectangle keeps its shape.
+type BlockSelection struct {
+	AnchorY   int
+	AnchorCol int
+	CursorCol int
+}
+
+// StartBlock enters block selection mode, reusing the anchor of an existing
+// selection if there is one.
+func (b *Buffer) StartBlock() {
+	anchorX, anchorY := b.CursorX, b.CursorY
+	if b.HasSelection() {
+		anchorX, anchorY = b.SelectX, b.SelectY
+	}
+
+	b.ClearExtraCursors()
+	b.ClearSelection()
+	b.Block = &BlockSelection{
+		AnchorY:   anchorY,
+		AnchorCol: ColumnAt(b.Lines[anchorY], anchorX, b.TabStop()),
+		CursorCol: ColumnAt(b.Lines[b.CursorY], b.CursorX, b.TabStop()),
+	}
+}
+
+func (b *Buffer) ClearBlock() {
+	b.Block = nil
+}
+
+// BlockBounds returns the rows and the half-open column range of the block.
+func (b *Buffer) BlockBounds() (startCol, startY, endCol, endY int) {
+	startY, endY = b.Block.AnchorY, b.CursorY
+	if startY > endY {
+		startY, endY = endY, startY
+	}
+	startCol, endCol = b.Block.AnchorCol, b.Block.CursorCol
+	if startCol > endCol {
+		startCol, endCol = endCol, startCol
+	}
+	return startCol, startY, endCol, endY
+}
+
+// InBlock reports whether display column col of line y is inside the block.
+func (b *Buffer) InBlock(col, y int) bool {
+	if b.Block == nil {
+		return false
+	}
+	startCol, startY, endCol, endY := b.BlockBounds()
+	return y >= startY && y <= endY && col >= startCol && col < endCol
+}
+
+// MoveBlock moves the block's cursor corner by dx columns and dy rows.
+func (b *Buffer) MoveBlock(dx, dy int) {
+	b.Block.CursorCol = max(b.Block.CursorCol+dx, 0)
+	b.CursorY = min(max(b.CursorY+dy, 0), len(b.Lines)-1)
+	b.CursorX = OffsetAtColumn(b.Lines[b.CursorY], b.Block.CursorCol, b.TabStop())
+}
+
+// blockRange returns the byte range of the block on line y.
+func (b *Buffer) blockRange(y, startCol, endCol int) (int, int) {
+	line := b.Lines[y]
+	return OffsetAtColumn(line, startCol, b.TabStop()), OffsetAtColumn(line, endCol, b.TabStop())
+}
+
+// BlockText returns the block's contents, one entry per row.
+func (b *Buffer) BlockText() []string {
+	startCol, startY, endCol, endY := b.BlockBounds()
+	rows := make([]string, 0, endY-startY+1)
+	for y := startY; y <= endY; y++ {
+		from, to := b.blockRange(y, startCol, endCol)
+		rows = append(rows, b.Lines[y][from:to])
+	}
+	return rows
+}
+
+// DeleteBlock removes the block's contents from every row, leaving a
+// zero-width block at its left edge.
+func (b *Buffer) DeleteBlock() {
+	startCol, startY, endCol, endY := b.BlockBounds()
+	for y := startY; y <= endY; y++ {
+		from, to := b.blockRange(y, startCol, endCol)
+		if from != to {
+			b.Lines[y] = b.Lines[y][:from] + b.Lines[y][to:]
+			b.Modified = true
+		}
+	}
+
+	b.Block.AnchorCol = startCol
+	b.Block.CursorCol = startCol
+	b.CursorX = OffsetAtColumn(b.Lines[b.CursorY], startCol, b.TabStop())
+}
+
+// BlockToCursors leaves block mode with one cursor per row at the block's
+// left edge. With pad, short lines are padded with spaces so every cursor
+// sits in the same column, ready for text to be inserted; without it, the
+// cursors on short lines stay at the line end and no line changes.
+func (b *Buffer) BlockToCursors(pad bool) {
+	startCol, startY, _, endY := b.BlockBounds()
+	primaryY := b.CursorY
+
+	b.ClearBlock()
+	b.extra = nil
+	for y := startY; y <= endY; y++ {
+		if padded := padToColumn(b.Lines[y], startCol, b.TabStop()); pad && padded != b.Lines[y] {
+			b.Lines[y] = padded
+			b.Modified = true
+		}
+		c := Cursor{CursorX: OffsetAtColumn(b.Lines[y], startCol, b.TabStop()), CursorY: y}
+		if y == primaryY {
+			b.Cursor = c
+		} else {
+			b.extra = append(b.extra, c)
+		}
+	}
+}
+
+// InsertBlock pastes rows as a rectangle starting at the cursor's display
+// column, one row per line, extending the buffer if it runs out of lines.
+func (b *Buffer) InsertBlock(rows []string) {
+	col := ColumnAt(b.GetCurrentLine(), b.CursorX, b.TabStop())
+	for i, text := range rows {
+		y := b.CursorY + i
+		if y >= len(b.Lines) {
+			b.Lines = append(b.Lines, "")
+		}
+		line := padToColumn(b.Lines[y], col, b.TabStop())
+		x := OffsetAtColumn(line, col, b.TabStop())
+		b.Lines[y] = line[:x] + text + line[x:]
+		if i == 0 {
+			b.CursorX = x + len(text)
+		}
+	}
+	b.Modified = true
+}
@@ -0,0 +1,124 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func newBlockBuffer(t *testing.T) *Buffer {
+	t.Helper()
+	b, _ := New("")
+	b.InsertText("name: a\nid\nkind: ccc")
+	b.CursorY, b.CursorX = 0, 2
+	b.StartBlock()
+	b.MoveBlock(2, 2)
+	return b
+}
+
+func TestBlockText(t *testing.T) {
+	b := newBlockBuffer(t)
+
+	rows := b.BlockText()
+	if len(rows) != 3 || rows[0] != "me" || rows[1] != "" || rows[2] != "nd" {
+		t.Errorf("Unexpected block rows %q", rows)
+	}
+}
+
+func TestDeleteBlock(t *testing.T) {
+	b := newBlockBuffer(t)
+	b.DeleteBlock()
+
+	if b.Lines[0] != "na: a" || b.Lines[1] != "id" || b.Lines[2] != "ki: ccc" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+
+	startCol, _, endCol, _ := b.BlockBounds()
+	if startCol != 2 || endCol != 2 {
+		t.Errorf("Expected zero-width block at column 2, got %d-%d", startCol, endCol)
+	}
+}
+
+func TestBlockToCursorsPadsShortLines(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("abcd\na\nabcd")
+	b.CursorY, b.CursorX = 0, 3
+	b.StartBlock()
+	b.MoveBlock(0, 2)
+
+	b.BlockToCursors(true)
+	b.ForEachCursor(func() { b.InsertRune('|') })
+
+	if b.Lines[0] != "abc|d" || b.Lines[1] != "a  |" || b.Lines[2] != "abc|d" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+	if b.Block != nil {
+		t.Error("Block selection should be cleared")
+	}
+}
+
+func TestBlockToCursorsWithoutPadding(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("abcd\na\nabcd")
+	b.Modified = false
+	b.CursorY, b.CursorX = 0, 3
+	b.StartBlock()
+	b.MoveBlock(0, 2)
+
+	b.BlockToCursors(false)
+
+	if b.Lines[1] != "a" || b.Modified {
+		t.Errorf("Expected the short line left alone, got %q (modified %v)", b.Lines, b.Modified)
+	}
+	want := map[int]int{0: 3, 1: 1, 2: 3}
+	for _, c := range b.Cursors() {
+		if want[c.CursorY] != c.CursorX {
+			t.Errorf("Expected the cursor on line %d at %d, got %d", c.CursorY, want[c.CursorY], c.CursorX)
+		}
+	}
+	if b.CursorCount() != 3 {
+		t.Errorf("Expected a cursor per row, got %d", b.CursorCount())
+	}
+}
+
+func TestInsertBlock(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("x = 1\ny")
+	b.CursorY, b.CursorX = 0, 1
+
+	b.InsertBlock([]string{"AA", "BB", "CC"})
+
+	if len(b.Lines) != 3 || b.Lines[0] != "xAA = 1" || b.Lines[1] != "yBB" || b.Lines[2] != " CC" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+}
+
+func TestColumnAtWideRunes(t *testing.T) {
+	line := "a世b"
+	if got := ColumnAt(line, len("a世"), 4); got != 3 {
+		t.Errorf("Expected column 3, got %d", got)
+	}
+	if got := OffsetAtColumn(line, 3, 4); got != len("a世") {
+		t.Errorf("Expected offset %d, got %d", len("a世"), got)
+	}
+	if got := OffsetAtColumnFloor(line, 2, 4); got != len("a") {
+		t.Errorf("Expected column inside wide rune to map to %d, got %d", len("a"), got)
+	}
+}
+
+func TestColumnAtTabStops(t *testing.T) {
+	line := "a\tbc\td"
+	if got := ColumnAt(line, 2, 4); got != 4 {
+		t.Errorf("Expected the tab to reach column 4, got %d", got)
+	}
+	if got := ColumnAt(line, 5, 4); got != 8 {
+		t.Errorf("Expected the second tab to reach column 8, got %d", got)
+	}
+	if got := LineWidth(line, 8); got != 17 {
+		t.Errorf("Expected width 17 with 8-column tabs, got %d", got)
+	}
+	if got := OffsetAtColumnFloor(line, 3, 4); got != 1 {
+		t.Errorf("Expected a column inside the tab to map to it, got %d", got)
+	}
+	if got := OffsetAtColumn(line, 3, 4); got != 2 {
+		t.Errorf("Expected the next rune after a column inside the tab, got %d", got)
+	}
+}
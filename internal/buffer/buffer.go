@@ -1,20 +1,37 @@
 package buffer
 
 import (
-	"bufio"
 	"os"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type Buffer struct {
-	Lines      []string
-	FilePath   string
-	Modified   bool
-	CursorX    int
-	CursorY    int
-	SelectMode bool
-	SelectX    int
-	SelectY    int
+	Lines    []string
+	FilePath string
+	Modified bool
+	// Cursor is the primary cursor. Its fields are promoted so most code
+	// can keep using b.CursorX, b.SelectMode and friends directly.
+	Cursor
+	// extra holds any additional cursors in multi-cursor mode.
+	extra []Cursor
+	// Block is the active rectangular selection, or nil.
+	Block *BlockSelection
+	// TabSize is the width of a tab stop. IndentSize is the width of one
+	// level of indentation, TabSize if unset; UseTabs indents with tab
+	// characters rather than spaces.
+	TabSize    int
+	IndentSize int
+	UseTabs    bool
+	// DetectedIndent is the style Load found the text indented in, or nil.
+	// Reindent replaces it with the style it converts to.
+	DetectedIndent *IndentStyle
+	// Format is how the text is stored on disk.
+	Format Format
+	// MaxLineLength, if set, is the column drawn as a ruler to show where
+	// lines get too long. Nothing is wrapped at it.
+	MaxLineLength int
 }
 
 func New(filePath string) (*Buffer, error) {
@@ -22,8 +39,7 @@ func New(filePath string) (*Buffer, error) {
 		Lines:    []string{""},
 		FilePath: filePath,
 		Modified: false,
-		CursorX:  0,
-		CursorY:  0,
+		Format:   DefaultFormat,
 	}
 
 	if filePath != "" {
@@ -35,50 +51,60 @@ func New(filePath string) (*Buffer, error) {
 	return b, nil
 }
 
+// Load reads the file, noting its line endings, charset and final newline
+// in Format, and indents new lines the way the file already is. Set
+// Format.Charset to CharsetLatin1 first to read Latin-1.
 func (b *Buffer) Load() error {
-	file, err := os.Open(b.FilePath)
+	data, err := os.ReadFile(b.FilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // New file
 		}
 		return err
 	}
-	defer file.Close()
 
-	b.Lines = []string{}
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		b.Lines = append(b.Lines, scanner.Text())
-	}
+	var text string
+	text, b.Format.Charset = decode(data, b.Format.Charset)
+	b.Lines = splitText(text, &b.Format)
 
-	if len(b.Lines) == 0 {
-		b.Lines = []string{""}
+	b.DetectedIndent = nil
+	if style, ok := DetectIndent(b.Lines); ok {
+		b.DetectedIndent = &style
+		b.SetIndentStyle(style)
 	}
-
-	return scanner.Err()
+	return nil
 }
 
+// Save writes the buffer in its Format.
 func (b *Buffer) Save() error {
-	file, err := os.Create(b.FilePath)
+	if b.Format.TrimTrailingWhitespace {
+		b.trimTrailingWhitespace()
+	}
+
+	data, err := encode(joinText(b.Lines, b.Format), b.Format.Charset)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	for i, line := range b.Lines {
-		if _, err := writer.WriteString(line); err != nil {
-			return err
-		}
-		if i < len(b.Lines)-1 {
-			if _, err := writer.WriteString("\n"); err != nil {
-				return err
-			}
-		}
+	if err := os.WriteFile(b.FilePath, data, 0644); err != nil {
+		return err
 	}
 
 	b.Modified = false
-	return writer.Flush()
+	return nil
+}
+
+// trimTrailingWhitespace strips spaces and tabs from the end of every
+// line, keeping cursors within their lines.
+func (b *Buffer) trimTrailingWhitespace() {
+	for y, line := range b.Lines {
+		b.Lines[y] = strings.TrimRight(line, " \t")
+	}
+	b.ForEachCursor(func() {
+		b.CursorX = min(b.CursorX, len(b.Lines[b.CursorY]))
+		if b.SelectMode {
+			b.SelectX = min(b.SelectX, len(b.Lines[b.SelectY]))
+		}
+	})
 }
 
 func (b *Buffer) InsertRune(r rune) {
@@ -142,6 +168,24 @@ func (b *Buffer) InsertNewline() {
 	b.Modified = true
 }
 
+// InsertIndentedNewline splits the line at the cursor like InsertNewline,
+// starting the new line with indent in place of the moved text's own
+// leading whitespace. A line left holding only whitespace is emptied so
+// auto-indentation does not leave trailing blanks behind.
+func (b *Buffer) InsertIndentedNewline(indent string) {
+	b.InsertNewline()
+	if prev := b.CursorY - 1; strings.TrimSpace(b.Lines[prev]) == "" {
+		b.Lines[prev] = ""
+	}
+	b.Lines[b.CursorY] = indent + strings.TrimLeft(b.Lines[b.CursorY], " \t")
+	b.CursorX = len(indent)
+}
+
+// Indentation returns the leading whitespace of line.
+func Indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
 func (b *Buffer) DeleteRune() {
 	if b.CursorY >= len(b.Lines) {
 		return
@@ -194,16 +238,43 @@ func (b *Buffer) GetCurrentLine() string {
 	return ""
 }
 
+// InsertText inserts text at the cursor in a single splice, so large pastes
+// stay linear in the size of the buffer.
 func (b *Buffer) InsertText(text string) {
-	lines := strings.Split(text, "\n")
-	for i, line := range lines {
-		if i > 0 {
-			b.InsertNewline()
-		}
-		for _, r := range line {
-			b.InsertRune(r)
-		}
+	if text == "" {
+		return
+	}
+	if b.CursorY >= len(b.Lines) {
+		b.Lines = append(b.Lines, "")
+		b.CursorY = len(b.Lines) - 1
+	}
+
+	line := b.Lines[b.CursorY]
+	if b.CursorX > len(line) {
+		b.CursorX = len(line)
+	}
+	before, after := line[:b.CursorX], line[b.CursorX:]
+
+	parts := strings.Split(text, "\n")
+	last := parts[len(parts)-1]
+	if len(parts) == 1 {
+		b.Lines[b.CursorY] = before + text + after
+		b.CursorX += len(text)
+		b.Modified = true
+		return
 	}
+
+	lines := make([]string, 0, len(b.Lines)+len(parts)-1)
+	lines = append(lines, b.Lines[:b.CursorY]...)
+	lines = append(lines, before+parts[0])
+	lines = append(lines, parts[1:len(parts)-1]...)
+	lines = append(lines, last+after)
+	lines = append(lines, b.Lines[b.CursorY+1:]...)
+
+	b.Lines = lines
+	b.CursorY += len(parts) - 1
+	b.CursorX = len(last)
+	b.Modified = true
 }
 
 func (b *Buffer) ReplaceCurrentLine(text string) {
@@ -218,18 +289,18 @@ func (b *Buffer) GetAllText() string {
 	return strings.Join(b.Lines, "\n")
 }
 
-func (b *Buffer) ToggleSelection() {
-	if b.SelectMode {
-		b.SelectMode = false
-	} else {
-		b.SelectMode = true
-		b.SelectX = b.CursorX
-		b.SelectY = b.CursorY
+// IsSelected reports whether the byte at column x of line y falls inside
+// the selection of any cursor.
+func (b *Buffer) IsSelected(x, y int) bool {
+	if b.Cursor.IsSelected(x, y) {
+		return true
 	}
-}
-
-func (b *Buffer) HasSelection() bool {
-	return b.SelectMode && (b.SelectX != b.CursorX || b.SelectY != b.CursorY)
+	for i := range b.extra {
+		if b.extra[i].IsSelected(x, y) {
+			return true
+		}
+	}
+	return false
 }
 
 func (b *Buffer) GetSelection() string {
@@ -237,13 +308,7 @@ func (b *Buffer) GetSelection() string {
 		return ""
 	}
 
-	startY, endY := b.SelectY, b.CursorY
-	startX, endX := b.SelectX, b.CursorX
-
-	if startY > endY || (startY == endY && startX > endX) {
-		startY, endY = endY, startY
-		startX, endX = endX, startX
-	}
+	startX, startY, endX, endY := b.SelectionBounds()
 
 	if startY == endY {
 		return b.Lines[startY][startX:endX]
@@ -271,13 +336,7 @@ func (b *Buffer) ReplaceSelection(text string) {
 		return
 	}
 
-	startY, endY := b.SelectY, b.CursorY
-	startX, endX := b.SelectX, b.CursorX
-
-	if startY > endY || (startY == endY && startX > endX) {
-		startY, endY = endY, startY
-		startX, endX = endX, startX
-	}
+	startX, startY, endX, endY := b.SelectionBounds()
 
 	newLines := strings.Split(text, "\n")
 
@@ -317,3 +376,224 @@ func (b *Buffer) ReplaceSelection(text string) {
 	b.SelectMode = false
 	b.Modified = true
 }
+
+func (b *Buffer) DeleteSelection() {
+	b.ReplaceSelection("")
+}
+
+// WrapSelection puts open before the selection and close after it, and
+// keeps the text between them selected.
+func (b *Buffer) WrapSelection(open, close string) {
+	if !b.HasSelection() {
+		return
+	}
+
+	startX, startY, endX, endY := b.SelectionBounds()
+	b.Lines[endY] = b.Lines[endY][:endX] + close + b.Lines[endY][endX:]
+	b.Lines[startY] = b.Lines[startY][:startX] + open + b.Lines[startY][startX:]
+	if startY == endY {
+		endX += len(open)
+	}
+	b.SelectX, b.SelectY = startX+len(open), startY
+	b.CursorX, b.CursorY = endX, endY
+	b.Modified = true
+}
+
+// SelectWord selects the word under (or just before) the cursor.
+func (b *Buffer) SelectWord() bool {
+	line := b.GetCurrentLine()
+	start, end := WordBounds(line, b.CursorX)
+	if start == end {
+		return false
+	}
+
+	b.SelectMode = true
+	b.SelectX, b.SelectY = start, b.CursorY
+	b.CursorX = end
+	return true
+}
+
+// SelectLine selects the current line including its newline. When whole
+// lines are already selected the selection grows by one more line.
+func (b *Buffer) SelectLine() {
+	startY, endY := b.CursorY, b.CursorY
+	if b.HasSelection() {
+		_, startY, _, endY = b.SelectionBounds()
+	}
+
+	b.SelectMode = true
+	b.SelectX, b.SelectY = 0, startY
+	if endY+1 < len(b.Lines) {
+		b.CursorX, b.CursorY = 0, endY+1
+	} else {
+		b.CursorY = len(b.Lines) - 1
+		b.CursorX = len(b.Lines[b.CursorY])
+	}
+}
+
+func (b *Buffer) SelectAll() {
+	b.SelectMode = true
+	b.SelectX, b.SelectY = 0, 0
+	b.CursorY = len(b.Lines) - 1
+	b.CursorX = len(b.Lines[b.CursorY])
+}
+
+// TabStop returns TabSize, or DefaultTabSize if it is unset.
+func (b *Buffer) TabStop() int {
+	if b.TabSize > 0 {
+		return b.TabSize
+	}
+	return DefaultTabSize
+}
+
+// IndentWidth returns the width of one level of indentation.
+func (b *Buffer) IndentWidth() int {
+	if b.IndentSize > 0 {
+		return b.IndentSize
+	}
+	return b.TabStop()
+}
+
+// IndentUnit returns the text of one level of indentation.
+func (b *Buffer) IndentUnit() string {
+	if b.UseTabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", b.IndentWidth())
+}
+
+// InsertTab inserts a tab, or with UseTabs off, spaces up to the next
+// indentation level.
+func (b *Buffer) InsertTab() {
+	if b.UseTabs {
+		b.InsertText("\t")
+		return
+	}
+	width := b.IndentWidth()
+	col := ColumnAt(b.GetCurrentLine(), b.CursorX, b.TabStop())
+	b.InsertText(strings.Repeat(" ", width-col%width))
+}
+
+// selectedLines returns the range of lines touched by the selection, or the
+// current line when nothing is selected. A selection ending at column 0
+// does not include that final line.
+func (b *Buffer) selectedLines() (int, int) {
+	if !b.HasSelection() {
+		return b.CursorY, b.CursorY
+	}
+
+	_, startY, endX, endY := b.SelectionBounds()
+	if endX == 0 && endY > startY {
+		endY--
+	}
+	return startY, endY
+}
+
+// IndentSelection prefixes every non-empty selected line with indent.
+func (b *Buffer) IndentSelection(indent string) {
+	startY, endY := b.selectedLines()
+	for y := startY; y <= endY; y++ {
+		if b.Lines[y] == "" {
+			continue
+		}
+		b.Lines[y] = indent + b.Lines[y]
+		if b.CursorY == y && b.CursorX > 0 {
+			b.CursorX += len(indent)
+		}
+		if b.SelectY == y && b.SelectX > 0 {
+			b.SelectX += len(indent)
+		}
+	}
+	b.Modified = true
+}
+
+// OutdentSelection removes one level of indentation (a tab or up to width
+// spaces) from every selected line.
+func (b *Buffer) OutdentSelection(width int) {
+	startY, endY := b.selectedLines()
+	for y := startY; y <= endY; y++ {
+		line := b.Lines[y]
+		n := 0
+		if strings.HasPrefix(line, "\t") {
+			n = 1
+		} else {
+			for n < width && n < len(line) && line[n] == ' ' {
+				n++
+			}
+		}
+		if n == 0 {
+			continue
+		}
+
+		b.Lines[y] = line[n:]
+		if b.CursorY == y {
+			b.CursorX = max(b.CursorX-n, 0)
+		}
+		if b.SelectY == y {
+			b.SelectX = max(b.SelectX-n, 0)
+		}
+		b.Modified = true
+	}
+}
+
+// DeleteForward deletes the rune under the cursor, joining the next line
+// when the cursor is at the end of the line.
+func (b *Buffer) DeleteForward() {
+	if b.CursorY >= len(b.Lines) {
+		return
+	}
+
+	line := b.Lines[b.CursorY]
+	if b.CursorX < len(line) {
+		_, size := utf8.DecodeRuneInString(line[b.CursorX:])
+		b.Lines[b.CursorY] = line[:b.CursorX] + line[b.CursorX+size:]
+		b.Modified = true
+	} else if b.CursorY < len(b.Lines)-1 {
+		b.Lines[b.CursorY] = line + b.Lines[b.CursorY+1]
+		b.Lines = append(b.Lines[:b.CursorY+1], b.Lines[b.CursorY+2:]...)
+		b.Modified = true
+	}
+}
+
+// IsWordRune reports whether r is part of an identifier-like word.
+func IsWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// WordBounds returns the byte range of the word touching column x in line.
+// If x is not on a word, the word ending at x is used; otherwise start == end.
+func WordBounds(line string, x int) (int, int) {
+	if x > len(line) {
+		x = len(line)
+	}
+
+	start := x
+	if x < len(line) {
+		if r, _ := utf8.DecodeRuneInString(line[x:]); !IsWordRune(r) {
+			if r, _ := utf8.DecodeLastRuneInString(line[:x]); x == 0 || !IsWordRune(r) {
+				return x, x
+			}
+		}
+	} else if r, _ := utf8.DecodeLastRuneInString(line[:x]); x == 0 || !IsWordRune(r) {
+		return x, x
+	}
+
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:start])
+		if !IsWordRune(r) {
+			break
+		}
+		start -= size
+	}
+
+	end := x
+	for end < len(line) {
+		r, size := utf8.DecodeRuneInString(line[end:])
+		if !IsWordRune(r) {
+			break
+		}
+		end += size
+	}
+
+	return start, end
+}
@@ -48,6 +48,41 @@ func TestInsertNewline(t *testing.T) {
 	}
 }
 
+func TestInsertIndentedNewline(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("\tif x {  y()")
+	b.CursorX = len("\tif x {")
+
+	b.InsertIndentedNewline("\t\t")
+	if b.Lines[0] != "\tif x {" || b.Lines[1] != "\t\ty()" || b.CursorX != 2 {
+		t.Errorf("Unexpected split %q with cursor at %d", b.Lines, b.CursorX)
+	}
+
+	// A line of indentation only is emptied when left behind
+	b.CursorX = 0
+	b.InsertText("\t\t")
+	b.InsertIndentedNewline("\t\t")
+	if b.Lines[1] != "" || b.Lines[2] != "\t\ty()" {
+		t.Errorf("Expected the blank line to lose its indentation, got %q", b.Lines)
+	}
+}
+
+func TestInsertTab(t *testing.T) {
+	b, _ := New("")
+	b.TabSize = 4
+	b.InsertText("ab")
+	b.InsertTab()
+	if b.Lines[0] != "ab  " {
+		t.Errorf("Expected spaces up to the tab stop, got %q", b.Lines[0])
+	}
+
+	b.UseTabs = true
+	b.InsertTab()
+	if b.Lines[0] != "ab  \t" || b.IndentUnit() != "\t" {
+		t.Errorf("Expected a tab character, got %q", b.Lines[0])
+	}
+}
+
 func TestDeleteRune(t *testing.T) {
 	b, _ := New("")
 	b.InsertRune('H')
@@ -123,6 +158,21 @@ func TestInsertText(t *testing.T) {
 	}
 }
 
+func TestInsertTextMidLine(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("head tail")
+	b.CursorX = 5
+
+	b.InsertText("one\ntwo\nthree ")
+
+	if len(b.Lines) != 3 || b.Lines[0] != "head one" || b.Lines[1] != "two" || b.Lines[2] != "three tail" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+	if b.CursorY != 2 || b.CursorX != len("three ") {
+		t.Errorf("Expected cursor at (6, 2), got (%d, %d)", b.CursorX, b.CursorY)
+	}
+}
+
 func TestReplaceCurrentLine(t *testing.T) {
 	b, _ := New("")
 	b.InsertRune('O')
@@ -134,3 +184,102 @@ func TestReplaceCurrentLine(t *testing.T) {
 		t.Errorf("Expected 'New', got '%s'", b.Lines[0])
 	}
 }
+
+func TestSelectWord(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("foo bar_baz qux")
+	b.CursorX = 6
+
+	if !b.SelectWord() {
+		t.Fatal("Expected a word to be selected")
+	}
+
+	if got := b.GetSelection(); got != "bar_baz" {
+		t.Errorf("Expected 'bar_baz', got '%s'", got)
+	}
+}
+
+func TestSelectLineGrows(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("one\ntwo\nthree")
+	b.CursorY = 0
+	b.CursorX = 1
+
+	b.SelectLine()
+	if got := b.GetSelection(); got != "one\n" {
+		t.Errorf("Expected 'one\\n', got '%s'", got)
+	}
+
+	b.SelectLine()
+	if got := b.GetSelection(); got != "one\ntwo\n" {
+		t.Errorf("Expected 'one\\ntwo\\n', got '%s'", got)
+	}
+
+	b.SelectLine()
+	if got := b.GetSelection(); got != "one\ntwo\nthree" {
+		t.Errorf("Expected whole buffer, got '%s'", got)
+	}
+}
+
+func TestDeleteSelection(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("Hello\nWorld")
+	b.CursorY, b.CursorX = 0, 2
+	b.StartSelection()
+	b.CursorY, b.CursorX = 1, 3
+
+	b.DeleteSelection()
+
+	if len(b.Lines) != 1 || b.Lines[0] != "Held" {
+		t.Errorf("Expected ['Held'], got %v", b.Lines)
+	}
+	if b.HasSelection() {
+		t.Error("Selection should be cleared after delete")
+	}
+}
+
+func TestWrapSelection(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("call x + y now\nand more")
+	b.CursorY, b.CursorX = 0, 5
+	b.StartSelection()
+	b.CursorX = 10
+
+	b.WrapSelection("(", ")")
+
+	if b.Lines[0] != "call (x + y) now" {
+		t.Errorf("Expected 'call (x + y) now', got %q", b.Lines[0])
+	}
+	if startX, _, endX, _ := b.SelectionBounds(); startX != 6 || endX != 11 {
+		t.Errorf("Expected x + y still selected, got %d-%d", startX, endX)
+	}
+
+	// Across lines the closer lands on the last line
+	b.SelectX, b.SelectY = 13, 0
+	b.CursorX, b.CursorY = 3, 1
+	b.WrapSelection("\"", "\"")
+	if b.Lines[0] != "call (x + y) \"now" || b.Lines[1] != "and\" more" {
+		t.Errorf("Expected quotes around now..and, got %q", b.Lines)
+	}
+	if b.SelectX != 14 || b.CursorX != 3 || b.CursorY != 1 {
+		t.Errorf("Expected the selection inside the quotes, got %d to (%d, %d)", b.SelectX, b.CursorX, b.CursorY)
+	}
+}
+
+func TestIndentAndOutdentSelection(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("a\n\nb\nc")
+	b.CursorY, b.CursorX = 0, 0
+	b.StartSelection()
+	b.CursorY, b.CursorX = 3, 0
+
+	b.IndentSelection("  ")
+	if b.Lines[0] != "  a" || b.Lines[1] != "" || b.Lines[2] != "  b" || b.Lines[3] != "c" {
+		t.Errorf("Unexpected indent result %q", b.Lines)
+	}
+
+	b.OutdentSelection(4)
+	if b.Lines[0] != "a" || b.Lines[2] != "b" {
+		t.Errorf("Unexpected outdent result %q", b.Lines)
+	}
+}
@@ -0,0 +1,80 @@
+package buffer
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultTabSize is the tab stop width used when none is configured.
+const DefaultTabSize = 4
+
+// RuneWidth returns the number of screen cells r occupies. Every rune takes
+// at least one cell, matching how the UI draws one rune per cell.
+func RuneWidth(r rune) int {
+	if w := runewidth.RuneWidth(r); w > 1 {
+		return w
+	}
+	return 1
+}
+
+// CellWidth returns the number of screen cells r occupies when drawn at
+// display column col: a tab reaches the next multiple of tabSize.
+func CellWidth(r rune, col, tabSize int) int {
+	if r == '\t' && tabSize > 0 {
+		return tabSize - col%tabSize
+	}
+	return RuneWidth(r)
+}
+
+// ColumnAt returns the display column of byte offset x in line.
+func ColumnAt(line string, x, tabSize int) int {
+	col := 0
+	for i, r := range line {
+		if i >= x {
+			break
+		}
+		col += CellWidth(r, col, tabSize)
+	}
+	return col
+}
+
+// OffsetAtColumn returns the byte offset of the first rune starting at or
+// after display column col, or len(line) if the line is shorter.
+func OffsetAtColumn(line string, col, tabSize int) int {
+	c := 0
+	for i, r := range line {
+		if c >= col {
+			return i
+		}
+		c += CellWidth(r, c, tabSize)
+	}
+	return len(line)
+}
+
+// OffsetAtColumnFloor returns the byte offset of the rune covering display
+// column col, so a column inside a wide rune or a tab maps to its start.
+func OffsetAtColumnFloor(line string, col, tabSize int) int {
+	c := 0
+	for i, r := range line {
+		w := CellWidth(r, c, tabSize)
+		if c+w > col {
+			return i
+		}
+		c += w
+	}
+	return len(line)
+}
+
+// LineWidth returns the display width of line.
+func LineWidth(line string, tabSize int) int {
+	return ColumnAt(line, len(line), tabSize)
+}
+
+// padToColumn extends line with spaces until it reaches display column col.
+func padToColumn(line string, col, tabSize int) string {
+	if w := LineWidth(line, tabSize); w < col {
+		return line + strings.Repeat(" ", col-w)
+	}
+	return line
+}
@@ -0,0 +1,322 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+)
+
+// Cursor is a caret position plus an optional selection anchor. Positions
+// are byte offsets into Buffer.Lines.
+type Cursor struct {
+	CursorX    int
+	CursorY    int
+	SelectMode bool
+	SelectX    int
+	SelectY    int
+}
+
+func (c *Cursor) ToggleSelection() {
+	if c.SelectMode {
+		c.SelectMode = false
+	} else {
+		c.SelectMode = true
+		c.SelectX = c.CursorX
+		c.SelectY = c.CursorY
+	}
+}
+
+// StartSelection anchors a selection at the cursor unless one is already
+// active.
+func (c *Cursor) StartSelection() {
+	if c.SelectMode {
+		return
+	}
+	c.SelectMode = true
+	c.SelectX = c.CursorX
+	c.SelectY = c.CursorY
+}
+
+func (c *Cursor) ClearSelection() {
+	c.SelectMode = false
+}
+
+func (c *Cursor) HasSelection() bool {
+	return c.SelectMode && (c.SelectX != c.CursorX || c.SelectY != c.CursorY)
+}
+
+// SelectionBounds returns the selection ordered from start to end,
+// regardless of which side the cursor is on.
+func (c *Cursor) SelectionBounds() (startX, startY, endX, endY int) {
+	startY, endY = c.SelectY, c.CursorY
+	startX, endX = c.SelectX, c.CursorX
+
+	if startY > endY || (startY == endY && startX > endX) {
+		startY, endY = endY, startY
+		startX, endX = endX, startX
+	}
+
+	return startX, startY, endX, endY
+}
+
+// IsSelected reports whether the byte at column x of line y falls inside
+// this cursor's selection.
+func (c *Cursor) IsSelected(x, y int) bool {
+	if !c.HasSelection() {
+		return false
+	}
+
+	startX, startY, endX, endY := c.SelectionBounds()
+	if y < startY || y > endY {
+		return false
+	}
+	if y == startY && x < startX {
+		return false
+	}
+	if y == endY && x >= endX {
+		return false
+	}
+	return true
+}
+
+// start returns the earliest position covered by the cursor, used to keep
+// cursors in document order.
+func (c *Cursor) start() (int, int) {
+	if c.HasSelection() {
+		x, y, _, _ := c.SelectionBounds()
+		return x, y
+	}
+	return c.CursorX, c.CursorY
+}
+
+// Cursors returns every cursor, primary first.
+func (b *Buffer) Cursors() []Cursor {
+	return append([]Cursor{b.Cursor}, b.extra...)
+}
+
+// SetCursors replaces every cursor; the first becomes the primary one.
+func (b *Buffer) SetCursors(cursors []Cursor) {
+	if len(cursors) == 0 {
+		return
+	}
+	b.Cursor = cursors[0]
+	b.extra = append([]Cursor(nil), cursors[1:]...)
+}
+
+func (b *Buffer) CursorCount() int {
+	return len(b.extra) + 1
+}
+
+func (b *Buffer) HasMultipleCursors() bool {
+	return len(b.extra) > 0
+}
+
+// ClearExtraCursors drops back to the primary cursor only.
+func (b *Buffer) ClearExtraCursors() {
+	b.extra = nil
+}
+
+// ForEachCursor runs fn once per cursor, in document order, with that
+// cursor temporarily installed as the primary one. fn may use any of the
+// single-cursor editing methods; cursors further down the buffer are
+// shifted to account for the text fn inserts or removes before them.
+func (b *Buffer) ForEachCursor(fn func()) {
+	if len(b.extra) == 0 {
+		fn()
+		return
+	}
+
+	all := b.Cursors()
+	order := make([]int, len(all))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		xi, yi := all[order[i]].start()
+		xj, yj := all[order[j]].start()
+		return yi < yj || (yi == yj && xi < xj)
+	})
+
+	for k, idx := range order {
+		// Text after a later cursor is untouched by this edit, so its
+		// distance from the end of its line and of the buffer is stable.
+		type anchor struct{ fromEnd, fromLineEnd int }
+		later := order[k+1:]
+		marks := make([][2]anchor, len(later))
+		for i, j := range later {
+			c := &all[j]
+			marks[i][0] = anchor{len(b.Lines) - c.CursorY, len(b.Lines[c.CursorY]) - c.CursorX}
+			if c.SelectMode {
+				marks[i][1] = anchor{len(b.Lines) - c.SelectY, len(b.Lines[c.SelectY]) - c.SelectX}
+			}
+		}
+
+		b.Cursor = all[idx]
+		fn()
+		all[idx] = b.Cursor
+
+		for i, j := range later {
+			c := &all[j]
+			c.CursorY, c.CursorX = b.restore(marks[i][0].fromEnd, marks[i][0].fromLineEnd)
+			if c.SelectMode {
+				c.SelectY, c.SelectX = b.restore(marks[i][1].fromEnd, marks[i][1].fromLineEnd)
+			}
+		}
+	}
+
+	b.Cursor = all[0]
+	b.extra = all[1:]
+	b.mergeCursors()
+}
+
+func (b *Buffer) restore(fromEnd, fromLineEnd int) (int, int) {
+	y := max(len(b.Lines)-fromEnd, 0)
+	x := max(len(b.Lines[y])-fromLineEnd, 0)
+	return y, x
+}
+
+// mergeCursors removes extra cursors that ended up on the same position as
+// an earlier one, e.g. after deleting the text between them.
+func (b *Buffer) mergeCursors() {
+	seen := map[[2]int]bool{{b.CursorX, b.CursorY}: true}
+	kept := b.extra[:0]
+	for _, c := range b.extra {
+		key := [2]int{c.CursorX, c.CursorY}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, c)
+	}
+	b.extra = kept
+}
+
+// addPrimary makes c the primary cursor, keeping the old primary as an
+// extra one, so the view follows the most recently added cursor.
+func (b *Buffer) addPrimary(c Cursor) {
+	b.extra = append(b.extra, b.Cursor)
+	b.Cursor = c
+}
+
+func (b *Buffer) hasCursorAt(x, y int) bool {
+	for _, c := range b.Cursors() {
+		if c.CursorX == x && c.CursorY == y {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCursorVertical adds a cursor on the line above (dir < 0) or below
+// (dir > 0) the outermost cursor in that direction.
+func (b *Buffer) AddCursorVertical(dir int) bool {
+	edge := b.Cursor
+	for _, c := range b.extra {
+		if (dir < 0 && c.CursorY < edge.CursorY) || (dir > 0 && c.CursorY > edge.CursorY) {
+			edge = c
+		}
+	}
+
+	y := edge.CursorY + dir
+	if y < 0 || y >= len(b.Lines) {
+		return false
+	}
+	x := min(edge.CursorX, len(b.Lines[y]))
+	if b.hasCursorAt(x, y) {
+		return false
+	}
+
+	b.addPrimary(Cursor{CursorX: x, CursorY: y})
+	return true
+}
+
+// AddNextOccurrence selects the next match of the primary selection as a
+// new cursor. With no selection it first selects the word under the cursor.
+func (b *Buffer) AddNextOccurrence() bool {
+	if !b.HasSelection() {
+		return b.SelectWord()
+	}
+
+	startX, startY, endX, endY := b.SelectionBounds()
+	if startY != endY {
+		return false
+	}
+	needle := b.Lines[startY][startX:endX]
+
+	taken := make(map[[2]int]bool)
+	for _, c := range b.Cursors() {
+		if c.HasSelection() {
+			x, y, _, _ := c.SelectionBounds()
+			taken[[2]int{x, y}] = true
+		}
+	}
+
+	// Search forward from the primary selection, wrapping at the end
+	for i := 0; i <= len(b.Lines); i++ {
+		y := (endY + i) % len(b.Lines)
+		line := b.Lines[y]
+		from := 0
+		if i == 0 {
+			from = endX
+		}
+		for from <= len(line) {
+			idx := strings.Index(line[from:], needle)
+			if idx < 0 {
+				break
+			}
+			x := from + idx
+			if !taken[[2]int{x, y}] {
+				b.addPrimary(Cursor{
+					CursorX:    x + len(needle),
+					CursorY:    y,
+					SelectMode: true,
+					SelectX:    x,
+					SelectY:    y,
+				})
+				return true
+			}
+			from = x + len(needle)
+		}
+	}
+
+	return false
+}
+
+// SplitSelectionIntoLines replaces a multi-line primary selection with one
+// cursor per line, each selecting that line's part of the original range.
+func (b *Buffer) SplitSelectionIntoLines() bool {
+	if !b.HasSelection() {
+		return false
+	}
+
+	startX, startY, endX, endY := b.SelectionBounds()
+	if startY == endY {
+		return false
+	}
+	if endX == 0 {
+		endY--
+		endX = len(b.Lines[endY])
+	}
+
+	var cursors []Cursor
+	for y := startY; y <= endY; y++ {
+		from, to := 0, len(b.Lines[y])
+		if y == startY {
+			from = startX
+		}
+		if y == endY {
+			to = endX
+		}
+		cursors = append(cursors, Cursor{
+			CursorX:    to,
+			CursorY:    y,
+			SelectMode: from != to,
+			SelectX:    from,
+			SelectY:    y,
+		})
+	}
+
+	b.Cursor = cursors[len(cursors)-1]
+	b.extra = append(b.extra, cursors[:len(cursors)-1]...)
+	b.mergeCursors()
+	return true
+}
@@ -0,0 +1,113 @@
+package buffer
+
+import (
+	"testing"
+)
+
+func TestForEachCursorInsert(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("ab ab\nab")
+	b.CursorY, b.CursorX = 0, 0
+	b.extra = []Cursor{{CursorX: 3, CursorY: 0}, {CursorX: 0, CursorY: 1}}
+
+	b.ForEachCursor(func() { b.InsertRune('x') })
+
+	if b.Lines[0] != "xab xab" || b.Lines[1] != "xab" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+	if b.CursorCount() != 3 {
+		t.Errorf("Expected 3 cursors, got %d", b.CursorCount())
+	}
+}
+
+func TestForEachCursorNewlineShiftsLaterCursors(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("abcdef")
+	b.CursorY, b.CursorX = 0, 2
+	b.extra = []Cursor{{CursorX: 4, CursorY: 0}}
+
+	b.ForEachCursor(func() { b.InsertNewline() })
+
+	if len(b.Lines) != 3 || b.Lines[0] != "ab" || b.Lines[1] != "cd" || b.Lines[2] != "ef" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+}
+
+func TestForEachCursorMergesCollidingCursors(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("abc")
+	b.CursorY, b.CursorX = 0, 1
+	b.extra = []Cursor{{CursorX: 2, CursorY: 0}}
+
+	b.ForEachCursor(func() { b.DeleteRune() })
+	b.ForEachCursor(func() { b.DeleteRune() })
+
+	if b.Lines[0] != "c" {
+		t.Errorf("Expected 'c', got '%s'", b.Lines[0])
+	}
+	if b.HasMultipleCursors() {
+		t.Error("Cursors at the same position should merge")
+	}
+}
+
+func TestAddNextOccurrence(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("foo bar\nfoo foo")
+	b.CursorY, b.CursorX = 0, 1
+
+	if !b.AddNextOccurrence() || b.GetSelection() != "foo" {
+		t.Fatalf("First call should select the word, got '%s'", b.GetSelection())
+	}
+	if !b.AddNextOccurrence() || !b.AddNextOccurrence() {
+		t.Fatal("Expected two more occurrences")
+	}
+	if b.AddNextOccurrence() {
+		t.Error("All occurrences are taken, expected false")
+	}
+	if b.CursorCount() != 3 {
+		t.Errorf("Expected 3 cursors, got %d", b.CursorCount())
+	}
+
+	b.ForEachCursor(func() { b.ReplaceSelection("x") })
+	if b.Lines[0] != "x bar" || b.Lines[1] != "x x" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+}
+
+func TestSplitSelectionIntoLines(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("one\ntwo\nthree")
+	b.SelectAll()
+
+	if !b.SplitSelectionIntoLines() {
+		t.Fatal("Expected selection to split")
+	}
+	if b.CursorCount() != 3 {
+		t.Errorf("Expected 3 cursors, got %d", b.CursorCount())
+	}
+
+	b.ForEachCursor(func() {
+		b.ClearSelection()
+		b.InsertRune(';')
+	})
+	if b.Lines[0] != "one;" || b.Lines[1] != "two;" || b.Lines[2] != "three;" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+}
+
+func TestAddCursorVertical(t *testing.T) {
+	b, _ := New("")
+	b.InsertText("long line\nab\nlong line")
+	b.CursorY, b.CursorX = 0, 5
+
+	b.AddCursorVertical(1)
+	b.AddCursorVertical(1)
+	if b.AddCursorVertical(1) {
+		t.Error("Should not add a cursor past the last line")
+	}
+
+	b.ForEachCursor(func() { b.InsertRune('|') })
+	if b.Lines[0] != "long |line" || b.Lines[1] != "ab|" || b.Lines[2] != "lo|ng line" {
+		t.Errorf("Unexpected lines %q", b.Lines)
+	}
+}
@@ -0,0 +1,134 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Charsets a buffer can be read and written in, named as in .editorconfig.
+const (
+	CharsetUTF8    = "utf-8"
+	CharsetUTF8BOM = "utf-8-bom"
+	CharsetLatin1  = "latin1"
+	CharsetUTF16BE = "utf-16be"
+	CharsetUTF16LE = "utf-16le"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16BE = []byte{0xfe, 0xff}
+	bomUTF16LE = []byte{0xff, 0xfe}
+)
+
+// Format is how a buffer's text is stored on disk. Load fills it in from
+// the file; Save writes the text back the same way.
+type Format struct {
+	// EOL is "\n" or "\r\n".
+	EOL string
+	// Charset is one of the Charset constants. Files with a byte order
+	// mark are recognised; Latin-1 has to be chosen before loading.
+	Charset string
+	// FinalNewline ends the file with EOL.
+	FinalNewline bool
+	// TrimTrailingWhitespace strips trailing spaces and tabs on save.
+	TrimTrailingWhitespace bool
+}
+
+// DefaultFormat is used for new files.
+var DefaultFormat = Format{EOL: "\n", Charset: CharsetUTF8}
+
+// EOLName returns "lf" or "crlf".
+func (f Format) EOLName() string {
+	if f.EOL == "\r\n" {
+		return "crlf"
+	}
+	return "lf"
+}
+
+// decode returns the text of data. A byte order mark decides the charset;
+// otherwise data is Latin-1 if charset says so and UTF-8 if not.
+func decode(data []byte, charset string) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return string(data[len(bomUTF8):]), CharsetUTF8BOM
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeUTF16(data[2:], binary.BigEndian), CharsetUTF16BE
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16(data[2:], binary.LittleEndian), CharsetUTF16LE
+	case charset == CharsetLatin1:
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes), CharsetLatin1
+	}
+	return string(data), CharsetUTF8
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// encode returns text in charset. Runes Latin-1 cannot hold are an error
+// rather than being lost.
+func encode(text, charset string) ([]byte, error) {
+	switch charset {
+	case CharsetUTF8BOM:
+		return append(append([]byte(nil), bomUTF8...), text...), nil
+	case CharsetUTF16BE, CharsetUTF16LE:
+		var order binary.ByteOrder = binary.BigEndian
+		bom := bomUTF16BE
+		if charset == CharsetUTF16LE {
+			order, bom = binary.LittleEndian, bomUTF16LE
+		}
+		units := utf16.Encode([]rune(text))
+		data := make([]byte, len(bom)+2*len(units))
+		copy(data, bom)
+		for i, u := range units {
+			order.PutUint16(data[len(bom)+2*i:], u)
+		}
+		return data, nil
+	case CharsetLatin1:
+		data := make([]byte, 0, len(text))
+		for i, r := range text {
+			if r > 0xff {
+				return nil, fmt.Errorf("%q at byte %d cannot be written as latin1", r, i)
+			}
+			data = append(data, byte(r))
+		}
+		return data, nil
+	}
+	return []byte(text), nil
+}
+
+// splitText splits file text into lines, filling in the line ending and
+// final newline of f.
+func splitText(text string, f *Format) []string {
+	f.EOL = "\n"
+	if i := strings.IndexByte(text, '\n'); i > 0 && text[i-1] == '\r' {
+		f.EOL = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	f.FinalNewline = strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// joinText is the inverse of splitText.
+func joinText(lines []string, f Format) string {
+	eol := f.EOL
+	if eol == "" {
+		eol = "\n"
+	}
+	text := strings.Join(lines, eol)
+	if f.FinalNewline {
+		text += eol
+	}
+	return text
+}
@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAndSaveKeepFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		charset string
+		lines   []string
+	}{
+		{"lf without final newline", []byte("a\nb"), CharsetUTF8, []string{"a", "b"}},
+		{"crlf with final newline", []byte("a\r\nb\r\n"), CharsetUTF8, []string{"a", "b"}},
+		{"utf-8 bom", []byte("\xef\xbb\xbfé\n"), CharsetUTF8BOM, []string{"é"}},
+		{"utf-16le", []byte{0xff, 0xfe, 'h', 0, 'i', 0, '\n', 0}, CharsetUTF16LE, []string{"hi"}},
+		{"utf-16be", []byte{0xfe, 0xff, 0, 'h', 0, 'i'}, CharsetUTF16BE, []string{"hi"}},
+		{"empty", nil, CharsetUTF8, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "f.txt")
+			os.WriteFile(path, tt.data, 0644)
+
+			b, err := New(path)
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			if b.Format.Charset != tt.charset || len(b.Lines) != len(tt.lines) || b.Lines[0] != tt.lines[0] {
+				t.Fatalf("Loaded %q as %s, want %q as %s", b.Lines, b.Format.Charset, tt.lines, tt.charset)
+			}
+
+			if err := b.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			if got, _ := os.ReadFile(path); !bytes.Equal(got, tt.data) {
+				t.Errorf("Expected an unchanged file %q, got %q", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestLatin1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	os.WriteFile(path, []byte("caf\xe9"), 0644)
+
+	b := &Buffer{FilePath: path, Format: Format{Charset: CharsetLatin1}}
+	if err := b.Load(); err != nil || b.Lines[0] != "café" {
+		t.Fatalf("Expected café, got %q (%v)", b.Lines, err)
+	}
+
+	b.Lines[0] = "naïve €"
+	if err := b.Save(); err == nil {
+		t.Error("Expected an error for a rune outside Latin-1")
+	}
+}
+
+func TestSaveConvertsFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	b, _ := New(path)
+	b.InsertText("a  \nb\t")
+	b.Format = Format{EOL: "\r\n", Charset: CharsetUTF8, FinalNewline: true, TrimTrailingWhitespace: true}
+
+	if err := b.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "a\r\nb\r\n" {
+		t.Errorf("Unexpected file %q", got)
+	}
+	if b.CursorX != 1 {
+		t.Errorf("Expected the cursor to stay within the trimmed line, got %d", b.CursorX)
+	}
+}
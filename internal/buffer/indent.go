@@ -0,0 +1,160 @@
+package buffer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDetectedWidth is the widest indentation level DetectIndent reports.
+const maxDetectedWidth = 8
+
+// IndentStyle is how a buffer's lines are indented.
+type IndentStyle struct {
+	Tabs bool
+	// Width is the number of spaces in one level; 0 with Tabs.
+	Width int
+}
+
+func (s IndentStyle) String() string {
+	if s.Tabs {
+		return "tabs"
+	}
+	if s.Width == 1 {
+		return "1 space"
+	}
+	return fmt.Sprintf("%d spaces", s.Width)
+}
+
+// unit returns the text of one level.
+func (s IndentStyle) unit() string {
+	if s.Tabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", s.Width)
+}
+
+// DetectIndent works out how lines are indented. Tabs win if more lines
+// start with a tab than with a space; the width of space indentation is the
+// step most often seen between a line and a more deeply indented successor.
+// It reports false when the lines give no evidence either way.
+func DetectIndent(lines []string) (IndentStyle, bool) {
+	var tabs, spaces int
+	var votes [maxDetectedWidth + 1]int
+	prev := 0
+	for _, line := range lines {
+		indent := Indentation(line)
+		if indent == line {
+			continue // Blank lines say nothing
+		}
+		if indent == "" {
+			prev = 0
+			continue
+		}
+		if indent[0] == '\t' {
+			tabs++
+			continue
+		}
+		if strings.ContainsRune(indent, '\t') {
+			continue // Mixed; tabs could be any width
+		}
+		// " * " continues a block comment rather than opening a level
+		if len(indent)%2 == 1 && strings.HasPrefix(line[len(indent):], "*") {
+			continue
+		}
+		spaces++
+		if step := len(indent) - prev; step > 0 && step <= maxDetectedWidth {
+			votes[step]++
+		}
+		prev = len(indent)
+	}
+
+	if tabs == 0 && spaces == 0 {
+		return IndentStyle{}, false
+	}
+	if tabs > spaces {
+		return IndentStyle{Tabs: true}, true
+	}
+	width := 0
+	for w := 1; w <= maxDetectedWidth; w++ {
+		if votes[w] > votes[width] {
+			width = w
+		}
+	}
+	if width == 0 {
+		return IndentStyle{}, false
+	}
+	return IndentStyle{Width: width}, true
+}
+
+// IndentStyle returns the style new indentation is made in.
+func (b *Buffer) IndentStyle() IndentStyle {
+	if b.UseTabs {
+		return IndentStyle{Tabs: true}
+	}
+	return IndentStyle{Width: b.IndentWidth()}
+}
+
+// SetIndentStyle makes new indentation follow s. The tab width is kept.
+func (b *Buffer) SetIndentStyle(s IndentStyle) {
+	b.UseTabs = s.Tabs
+	if !s.Tabs {
+		b.IndentSize = s.Width
+	}
+}
+
+// Reindent rewrites the indentation of every line from the buffer's style
+// into s, keeping the number of levels, and then switches to s. Columns
+// left over from a partial level stay as spaces. It returns the number of
+// lines changed.
+func (b *Buffer) Reindent(s IndentStyle) int {
+	tabSize := b.TabStop()
+	width := b.IndentWidth()
+	if b.UseTabs {
+		width = tabSize
+	}
+
+	changed := 0
+	for y, line := range b.Lines {
+		indent := Indentation(line)
+		if indent == "" {
+			continue
+		}
+		cols := LineWidth(indent, tabSize)
+		replaced := strings.Repeat(s.unit(), cols/width) + strings.Repeat(" ", cols%width)
+		if replaced == indent {
+			continue
+		}
+
+		b.Lines[y] = replaced + line[len(indent):]
+		shift := func(c *Cursor) {
+			if c.CursorY == y {
+				c.CursorX = reindentX(c.CursorX, len(indent), len(replaced))
+			}
+			if c.SelectY == y {
+				c.SelectX = reindentX(c.SelectX, len(indent), len(replaced))
+			}
+		}
+		shift(&b.Cursor)
+		for i := range b.extra {
+			shift(&b.extra[i])
+		}
+		changed++
+	}
+
+	if changed > 0 {
+		b.Modified = true
+	}
+	b.SetIndentStyle(s)
+	b.DetectedIndent = &s
+	return changed
+}
+
+// reindentX moves offset x on a line whose indentation changed length from
+// from to to: past the indentation it keeps its place in the text, inside
+// it it stays put as far as the new indentation allows.
+func reindentX(x, from, to int) int {
+	if x >= from {
+		return x + to - from
+	}
+	return min(x, to)
+}
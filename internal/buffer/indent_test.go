@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectIndent(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  IndentStyle
+		ok    bool
+	}{
+		{"two spaces", []string{"a:", "  b:", "    c: 1", "  d: 2", "e: 3"}, IndentStyle{Width: 2}, true},
+		{"four spaces", []string{"def f():", "    if x:", "        return 1", "", "    return 2"}, IndentStyle{Width: 4}, true},
+		{"tabs", []string{"func f() {", "\tif x {", "\t\treturn", "\t}", "}"}, IndentStyle{Tabs: true}, true},
+		{"block comment", []string{"/*", " * doc", " */", "int f() {", "  return 0;", "}"}, IndentStyle{Width: 2}, true},
+		{"mostly tabs", []string{"{", "\ta", "\tb", "  c", "}"}, IndentStyle{Tabs: true}, true},
+		{"flat", []string{"a", "b", "", "c"}, IndentStyle{}, false},
+		{"blank indented", []string{"a", "    ", "b"}, IndentStyle{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DetectIndent(tt.lines)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Expected %v, %v, got %v, %v", tt.want, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestLoadDetectsIndent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.py")
+	os.WriteFile(path, []byte("if x:\n  y = 1\n"), 0644)
+
+	b, err := New(path)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if b.DetectedIndent == nil || *b.DetectedIndent != (IndentStyle{Width: 2}) {
+		t.Fatalf("Expected 2 spaces detected, got %v", b.DetectedIndent)
+	}
+	if b.IndentUnit() != "  " {
+		t.Errorf("Expected new indentation of 2 spaces, got %q", b.IndentUnit())
+	}
+}
+
+func TestReindent(t *testing.T) {
+	b := &Buffer{Lines: []string{"a {", "  b {", "    c", "     d", "  }", "}"}, IndentSize: 2}
+	b.CursorY, b.CursorX = 2, 5
+
+	if n := b.Reindent(IndentStyle{Tabs: true}); n != 4 {
+		t.Errorf("Expected 4 lines changed, got %d", n)
+	}
+	want := []string{"a {", "\tb {", "\t\tc", "\t\t d", "\t}", "}"}
+	if !reflect.DeepEqual(b.Lines, want) {
+		t.Fatalf("Expected %q, got %q", want, b.Lines)
+	}
+	if b.CursorX != 3 || !b.UseTabs || !b.Modified {
+		t.Errorf("Expected the cursor after c and tabs in use, got x=%d tabs=%v", b.CursorX, b.UseTabs)
+	}
+
+	// And back, a tab being one level
+	b.Reindent(IndentStyle{Width: 4})
+	want = []string{"a {", "    b {", "        c", "         d", "    }", "}"}
+	if !reflect.DeepEqual(b.Lines, want) {
+		t.Errorf("Expected %q, got %q", want, b.Lines)
+	}
+	if b.IndentUnit() != "    " || *b.DetectedIndent != (IndentStyle{Width: 4}) {
+		t.Errorf("Expected 4 spaces in use, got %q", b.IndentUnit())
+	}
+}
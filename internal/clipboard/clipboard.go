@@ -0,0 +1,120 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// ErrUnsupported is returned by backends that cannot perform an operation,
+// e.g. reading through OSC 52. Callers fall back to the internal clipboard.
+var ErrUnsupported = errors.New("clipboard operation not supported")
+
+// osc52MaxBytes caps the encoded payload; many terminals silently drop
+// larger OSC 52 sequences.
+const osc52MaxBytes = 100000
+
+// Backend reads and writes a clipboard outside the editor.
+type Backend interface {
+	Name() string
+	Write(text string) error
+	Read() (string, error)
+}
+
+// New returns the backend selected by name: "system", "osc52", "internal",
+// or "auto"/"" to pick one from the environment. OSC 52 sequences are
+// written to w.
+func New(name string, w io.Writer) (Backend, error) {
+	switch name {
+	case "system":
+		return systemBackend{}, nil
+	case "osc52":
+		return NewOSC52(w), nil
+	case "internal":
+		return internalBackend{}, nil
+	case "", "auto":
+		return Detect(w), nil
+	default:
+		return nil, fmt.Errorf("unknown clipboard backend %q (use auto, system, osc52 or internal)", name)
+	}
+}
+
+// Detect prefers OSC 52 over SSH, where xclip/pbcopy would reach the remote
+// machine's clipboard, and when no system clipboard tool is installed.
+func Detect(w io.Writer) Backend {
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return NewOSC52(w)
+	}
+	if clipboard.Unsupported {
+		return NewOSC52(w)
+	}
+	return systemBackend{}
+}
+
+type systemBackend struct{}
+
+func (systemBackend) Name() string { return "system" }
+
+func (systemBackend) Write(text string) error {
+	return clipboard.WriteAll(text)
+}
+
+func (systemBackend) Read() (string, error) {
+	return clipboard.ReadAll()
+}
+
+// OSC52 copies by asking the terminal to set its clipboard with an OSC 52
+// escape sequence, which works across SSH. Inside tmux the sequence is
+// wrapped in a DCS passthrough so it reaches the outer terminal.
+type OSC52 struct {
+	w    io.Writer
+	Tmux bool
+}
+
+func NewOSC52(w io.Writer) *OSC52 {
+	return &OSC52{w: w, Tmux: os.Getenv("TMUX") != ""}
+}
+
+func (o *OSC52) Name() string { return "osc52" }
+
+func (o *OSC52) Write(text string) error {
+	seq, err := o.Sequence(text)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(o.w, seq)
+	return err
+}
+
+// Read is not supported: most terminals disable OSC 52 queries.
+func (o *OSC52) Read() (string, error) {
+	return "", ErrUnsupported
+}
+
+// Sequence returns the escape sequence that copies text.
+func (o *OSC52) Sequence(text string) (string, error) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+	if len(encoded) > osc52MaxBytes {
+		return "", fmt.Errorf("selection too large for OSC 52 (%d bytes encoded)", len(encoded))
+	}
+
+	seq := "\x1b]52;c;" + encoded + "\a"
+	if o.Tmux {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return seq, nil
+}
+
+// internalBackend keeps everything inside finpup.
+type internalBackend struct{}
+
+func (internalBackend) Name() string { return "internal" }
+
+func (internalBackend) Write(string) error { return ErrUnsupported }
+
+func (internalBackend) Read() (string, error) { return "", ErrUnsupported }
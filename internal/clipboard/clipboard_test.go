@@ -0,0 +1,55 @@
+package clipboard
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOSC52Sequence(t *testing.T) {
+	var out bytes.Buffer
+	o := &OSC52{w: &out}
+
+	if err := o.Write("hi"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if got, want := out.String(), "\x1b]52;c;aGk=\a"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestOSC52TmuxPassthrough(t *testing.T) {
+	o := &OSC52{Tmux: true}
+
+	seq, err := o.Sequence("hi")
+	if err != nil {
+		t.Fatalf("Sequence failed: %v", err)
+	}
+
+	if want := "\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\"; seq != want {
+		t.Errorf("Expected %q, got %q", want, seq)
+	}
+}
+
+func TestOSC52RejectsOversizedText(t *testing.T) {
+	o := &OSC52{}
+	if _, err := o.Sequence(string(make([]byte, osc52MaxBytes))); err == nil {
+		t.Error("Expected error for oversized selection")
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	for _, name := range []string{"system", "osc52", "internal"} {
+		b, err := New(name, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("New(%q) failed: %v", name, err)
+		}
+		if b.Name() != name {
+			t.Errorf("Expected backend %q, got %q", name, b.Name())
+		}
+	}
+
+	if _, err := New("bogus", nil); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,6 +13,7 @@ type Config struct {
 	AI    AIConfig    `yaml:"ai"`
 	Theme ThemeConfig `yaml:"theme"`
 	Editor EditorConfig `yaml:"editor"`
+	Clipboard ClipboardConfig `yaml:"clipboard"`
 }
 
 type AIConfig struct {
@@ -23,12 +26,84 @@ type AIConfig struct {
 
 type ThemeConfig struct {
 	Current string `yaml:"current"` // dark, light, monokai, solarized
+	// SyntaxStyle, if set, names a chroma style used for all token colours
+	// instead of the theme's own.
+	SyntaxStyle string `yaml:"syntax_style,omitempty"`
+	// ColorMode is auto, truecolor, 256, 16 or mono.
+	ColorMode string `yaml:"color_mode"`
+}
+
+type ClipboardConfig struct {
+	Backend string `yaml:"backend"` // auto, system, osc52, internal
 }
 
 type EditorConfig struct {
-	TabSize      int  `yaml:"tab_size"`
-	ShowLineNums bool `yaml:"show_line_numbers"`
-	AutoIndent   bool `yaml:"auto_indent"`
+	TabSize int `yaml:"tab_size"`
+	// UseTabs indents with tab characters instead of TabSize spaces.
+	UseTabs      bool        `yaml:"use_tabs"`
+	ShowLineNums LineNumbers `yaml:"show_line_numbers"`
+	AutoIndent   bool        `yaml:"auto_indent"`
+	// Structural enables the syntax-tree layer for Go, JSON, YAML and HCL.
+	Structural bool `yaml:"structural"`
+	// Languages maps file name globs to highlighting languages, e.g.
+	// "Jenkinsfile": groovy. They take precedence over detection.
+	Languages map[string]string `yaml:"languages,omitempty"`
+	// SoftWrap wraps long lines at word boundaries in every file;
+	// WrapLanguages turns it on for the named languages only.
+	SoftWrap      bool     `yaml:"soft_wrap"`
+	WrapLanguages []string `yaml:"wrap_languages"`
+	// SideScrollMargin is how many columns are kept visible beside the
+	// cursor when long lines scroll sideways.
+	SideScrollMargin int `yaml:"side_scroll_margin"`
+	// ShowWhitespace draws tabs, trailing spaces and non-breaking spaces
+	// with the theme's glyphs.
+	ShowWhitespace bool `yaml:"show_whitespace"`
+	// IndentGuides draws a guide at each indentation level.
+	IndentGuides bool `yaml:"indent_guides"`
+	// Rulers are columns to mark with a vertical line, e.g. [80, 120].
+	Rulers []int `yaml:"rulers,omitempty"`
+	// AutoClose types the closing bracket or quote along with the
+	// opening one. AutoClosePairs overrides the pairs for a language,
+	// keyed by lower-case name, as a string of opener-closer pairs such
+	// as "()[]{}\"\"". An empty string turns it off for that language.
+	AutoClose      bool              `yaml:"auto_close"`
+	AutoClosePairs map[string]string `yaml:"auto_close_pairs,omitempty"`
+}
+
+// LineNumbers is how the gutter numbers lines.
+type LineNumbers string
+
+const (
+	LineNumbersAbsolute LineNumbers = "absolute"
+	// LineNumbersRelative shows each line's distance from the cursor.
+	LineNumbersRelative LineNumbers = "relative"
+	// LineNumbersHybrid is relative, except for the cursor's own line.
+	LineNumbersHybrid LineNumbers = "hybrid"
+	LineNumbersOff    LineNumbers = "off"
+)
+
+// UnmarshalYAML accepts a mode name, or true and false from configs
+// written when line numbers could only be switched on or off.
+func (l *LineNumbers) UnmarshalYAML(node *yaml.Node) error {
+	var on bool
+	if err := node.Decode(&on); err == nil {
+		*l = LineNumbersOff
+		if on {
+			*l = LineNumbersAbsolute
+		}
+		return nil
+	}
+
+	var mode string
+	if err := node.Decode(&mode); err != nil {
+		return err
+	}
+	switch LineNumbers(strings.ToLower(mode)) {
+	case LineNumbersAbsolute, LineNumbersRelative, LineNumbersHybrid, LineNumbersOff:
+		*l = LineNumbers(strings.ToLower(mode))
+		return nil
+	}
+	return fmt.Errorf("line %d: show_line_numbers: unknown mode %q (use absolute, relative, hybrid or off)", node.Line, mode)
 }
 
 var DefaultConfig = Config{
@@ -39,12 +114,20 @@ var DefaultConfig = Config{
 		Model:    "llama3.2",
 	},
 	Theme: ThemeConfig{
-		Current: "dark",
+		Current:   "dark",
+		ColorMode: "auto",
 	},
 	Editor: EditorConfig{
-		TabSize:      4,
-		ShowLineNums: true,
-		AutoIndent:   true,
+		TabSize:          4,
+		ShowLineNums:     LineNumbersAbsolute,
+		AutoIndent:       true,
+		Structural:       true,
+		WrapLanguages:    []string{"markdown", "plaintext"},
+		SideScrollMargin: 5,
+		AutoClose:        true,
+	},
+	Clipboard: ClipboardConfig{
+		Backend: "auto",
 	},
 }
 
@@ -63,7 +146,9 @@ func Load() (*Config, error) {
 		return &DefaultConfig, nil
 	}
 
-	var cfg Config
+	// Start from the defaults so options added since the file was written
+	// keep their default values
+	cfg := DefaultConfig
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return &DefaultConfig, err
 	}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoad(t *testing.T) {
@@ -15,11 +19,35 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadKeepsDefaultsForMissingKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.WriteFile(filepath.Join(home, ".finpup.yaml"), []byte("editor:\n  tab_size: 2\n"), 0644)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Editor.TabSize != 2 {
+		t.Errorf("Expected tab size 2 from the file, got %d", cfg.Editor.TabSize)
+	}
+	if !cfg.Editor.Structural {
+		t.Error("Expected structural to default to true when the file leaves it out")
+	}
+	if cfg.Theme.Current != "dark" {
+		t.Errorf("Expected the default theme, got %q", cfg.Theme.Current)
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	if DefaultConfig.Editor.TabSize != 4 {
 		t.Errorf("Expected tab size 4, got %d", DefaultConfig.Editor.TabSize)
 	}
 
+	if !DefaultConfig.Editor.AutoClose {
+		t.Error("Expected brackets to auto-close by default")
+	}
+
 	if DefaultConfig.Theme.Current != "dark" {
 		t.Errorf("Expected theme 'dark', got '%s'", DefaultConfig.Theme.Current)
 	}
@@ -27,6 +55,10 @@ func TestDefaultConfig(t *testing.T) {
 	if DefaultConfig.AI.Provider != "ollama" {
 		t.Errorf("Expected AI provider 'ollama', got '%s'", DefaultConfig.AI.Provider)
 	}
+
+	if DefaultConfig.Clipboard.Backend != "auto" {
+		t.Errorf("Expected clipboard backend 'auto', got '%s'", DefaultConfig.Clipboard.Backend)
+	}
 }
 
 func TestSave(t *testing.T) {
@@ -39,3 +71,28 @@ func TestSave(t *testing.T) {
 	// In real usage, this saves to ~/.finpup.yaml
 	_ = Save(&testCfg)
 }
+
+func TestLineNumbersYAML(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LineNumbers
+	}{
+		{"true", LineNumbersAbsolute},
+		{"false", LineNumbersOff},
+		{"relative", LineNumbersRelative},
+		{"Hybrid", LineNumbersHybrid},
+	}
+	for _, tt := range tests {
+		var cfg EditorConfig
+		if err := yaml.Unmarshal([]byte("show_line_numbers: "+tt.in), &cfg); err != nil {
+			t.Errorf("%s: unexpected error %v", tt.in, err)
+		} else if cfg.ShowLineNums != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.in, tt.want, cfg.ShowLineNums)
+		}
+	}
+
+	var cfg EditorConfig
+	if err := yaml.Unmarshal([]byte("show_line_numbers: sideways"), &cfg); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
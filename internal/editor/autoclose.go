@@ -0,0 +1,150 @@
+package editor
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+)
+
+// defaultPairs are auto-closed in languages without their own list. Each
+// pair is an opener followed by its closer.
+const defaultPairs = "()[]{}\"\"''``"
+
+// blockPairs expand into an indented block when Enter is pressed between
+// them.
+const blockPairs = "()[]{}"
+
+// languagePairs leave out quotes that a language uses on their own, such
+// as Rust lifetimes or apostrophes in prose. Keyed by lower-case name.
+var languagePairs = map[string]string{
+	"rust":        "()[]{}\"\"",
+	"ocaml":       "()[]{}\"\"",
+	"clojure":     "()[]{}\"\"",
+	"common lisp": "()[]{}\"\"",
+	"scheme":      "()[]{}\"\"",
+	"emacslisp":   "()[]{}\"\"",
+	"markdown":    "()[]{}\"\"``",
+	"plaintext":   "()[]{}\"\"",
+}
+
+// pairs returns the pairs to auto-close in the buffer's language, or ""
+// when auto-closing is off.
+func (e *Editor) pairs() string {
+	if !e.config.Editor.AutoClose {
+		return ""
+	}
+	language := strings.ToLower(e.ui.Language())
+	if pairs, ok := e.config.Editor.AutoClosePairs[language]; ok {
+		return pairs
+	}
+	if pairs, ok := languagePairs[language]; ok {
+		return pairs
+	}
+	return defaultPairs
+}
+
+// closerFor returns the closer pairs gives for open.
+func closerFor(pairs string, open rune) (rune, bool) {
+	runes := []rune(pairs)
+	for i := 0; i+1 < len(runes); i += 2 {
+		if runes[i] == open {
+			return runes[i+1], true
+		}
+	}
+	return 0, false
+}
+
+// isCloser reports whether r closes one of pairs.
+func isCloser(pairs string, r rune) bool {
+	runes := []rune(pairs)
+	for i := 1; i < len(runes); i += 2 {
+		if runes[i] == r {
+			return true
+		}
+	}
+	return false
+}
+
+// runesAround returns the runes either side of the cursor, 0 at the ends
+// of the line.
+func (e *Editor) runesAround() (before, after rune) {
+	line := e.buffer.GetCurrentLine()
+	x := min(e.buffer.CursorX, len(line))
+	if x > 0 {
+		before, _ = utf8.DecodeLastRuneInString(line[:x])
+	}
+	if x < len(line) {
+		after, _ = utf8.DecodeRuneInString(line[x:])
+	}
+	return before, after
+}
+
+// wrapSelection surrounds the selection with r and its closer when r is
+// an opener.
+func (e *Editor) wrapSelection(r rune) bool {
+	closer, ok := closerFor(e.pairs(), r)
+	if !ok {
+		return false
+	}
+	e.buffer.WrapSelection(string(r), string(closer))
+	e.transientSelection = true
+	return true
+}
+
+// overtype steps over the closer at the cursor when the same closer is
+// typed, rather than doubling it.
+func (e *Editor) overtype(r rune) bool {
+	pairs := e.pairs()
+	if _, after := e.runesAround(); after != r || !isCloser(pairs, r) {
+		return false
+	}
+	e.buffer.CursorX += utf8.RuneLen(r)
+	return true
+}
+
+// autoClose types r, an opener, together with its closer and leaves the
+// cursor between them. It only does so where a closer is likely wanted:
+// before space, a closer or the end of the line, and for quotes not
+// straight after a word, so "don't" stays as typed.
+func (e *Editor) autoClose(r rune) bool {
+	pairs := e.pairs()
+	closer, ok := closerFor(pairs, r)
+	if !ok {
+		return false
+	}
+	before, after := e.runesAround()
+	if after != 0 && !unicode.IsSpace(after) && !isCloser(pairs, after) {
+		return false
+	}
+	if closer == r && (before == r || buffer.IsWordRune(before)) {
+		return false
+	}
+
+	e.buffer.InsertText(string(r) + string(closer))
+	e.buffer.CursorX -= utf8.RuneLen(closer)
+	return true
+}
+
+// deletePair removes an empty pair around the cursor on Backspace.
+func (e *Editor) deletePair() bool {
+	before, after := e.runesAround()
+	if closer, ok := closerFor(e.pairs(), before); !ok || closer != after {
+		return false
+	}
+	line := e.buffer.GetCurrentLine()
+	x := e.buffer.CursorX - utf8.RuneLen(before)
+	e.buffer.Lines[e.buffer.CursorY] = line[:x] + line[e.buffer.CursorX+utf8.RuneLen(after):]
+	e.buffer.CursorX = x
+	e.buffer.Modified = true
+	return true
+}
+
+// expandsBlock reports whether the cursor sits between brackets that
+// Enter should open up into an indented block.
+func (e *Editor) expandsBlock() bool {
+	before, after := e.runesAround()
+	closer, ok := closerFor(blockPairs, before)
+	return ok && after == closer
+}
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+	"github.com/justynroberts/finpup/internal/ui"
+)
+
+// newTestEditor opens text as path on a simulation screen. A "|" in text
+// marks the cursor.
+func newTestEditor(t *testing.T, path, text string, cfg config.Config) *Editor {
+	t.Helper()
+	return newTestEditorOn(t, tcell.NewSimulationScreen(""), path, text, cfg)
+}
+
+// newTestEditorOn is newTestEditor on a given screen, for tests that
+// inject the keys a prompt reads.
+func newTestEditorOn(t *testing.T, screen tcell.SimulationScreen, path, text string, cfg config.Config) *Editor {
+	t.Helper()
+	buf, _ := buffer.New("")
+	buf.FilePath = path
+	x := strings.Index(text, "|")
+	buf.InsertText(strings.Replace(text, "|", "", 1))
+	buf.CursorX, buf.CursorY = x, 0
+
+	u, err := ui.NewWithScreen(screen, buf, &cfg)
+	if err != nil {
+		t.Fatalf("NewWithScreen failed: %v", err)
+	}
+	t.Cleanup(u.Close)
+	return &Editor{buffer: buf, ui: u, config: &cfg, insertMode: true}
+}
+
+// typeKeys sends each rune of keys, with '\b' standing for Backspace.
+func typeKeys(e *Editor, keys string) {
+	for _, r := range keys {
+		if r == '\b' {
+			e.handleEvent(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone))
+			continue
+		}
+		e.handleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+}
+
+// cursorLine returns the current line with "|" at the cursor.
+func cursorLine(e *Editor) string {
+	line := e.buffer.GetCurrentLine()
+	return line[:e.buffer.CursorX] + "|" + line[e.buffer.CursorX:]
+}
+
+func TestAutoClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		pairs map[string]string
+		off   bool
+		text  string
+		keys  string
+		want  string
+	}{
+		{"opener inserts pair", "a.go", nil, false, "f|", "(", "f(|)"},
+		{"quote inserts pair", "a.go", nil, false, "x = |", `"`, `x = "|"`},
+		{"closer steps over", "a.go", nil, false, "f|", "(a)", "f(a)|"},
+		{"quote steps over", "a.go", nil, false, "|", `"hi"`, `"hi"|`},
+		{"backspace deletes empty pair", "a.go", nil, false, "f|", "(\b", "f|"},
+		{"backspace removes text then pair", "a.go", nil, false, "f|", "(a\b\b", "f|"},
+		{"backspace inside text", "a.go", nil, false, "(a|)", "\b", "(|)"},
+		{"not before a word", "a.go", nil, false, "|foo", "(", "(|foo"},
+		{"quote not before a word", "a.go", nil, false, "|foo", `"`, `"|foo`},
+		{"quote not after a word", "a.md", nil, false, "don|", "'", "don'|"},
+		{"language without single quotes", "a.rs", nil, false, "&|", "'", "&'|"},
+		{"override turns it off", "a.go", map[string]string{"go": ""}, false, "f|", "(", "f(|"},
+		{"override replaces pairs", "a.go", map[string]string{"go": "<>"}, false, "f|", "<(", "f<(|>"},
+		{"override for another language", "a.go", map[string]string{"rust": ""}, false, "f|", "[", "f[|]"},
+		{"auto_close off", "a.go", nil, true, "f|", "(", "f(|"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig
+			cfg.Editor.AutoClosePairs = tt.pairs
+			cfg.Editor.AutoClose = !tt.off
+			e := newTestEditor(t, tt.path, tt.text, cfg)
+
+			typeKeys(e, tt.keys)
+			if got := cursorLine(e); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAutoCloseWrapsSelection(t *testing.T) {
+	e := newTestEditor(t, "a.go", "call |x + y now", config.DefaultConfig)
+	e.buffer.StartSelection()
+	e.buffer.CursorX += len("x + y")
+
+	typeKeys(e, "(")
+	if got := e.buffer.GetCurrentLine(); got != "call (x + y) now" {
+		t.Fatalf("Expected the selection wrapped, got %q", got)
+	}
+	// The text stays selected, so a second opener wraps it again
+	typeKeys(e, "[")
+	if got := e.buffer.GetCurrentLine(); got != "call ([x + y]) now" {
+		t.Errorf("Expected a second wrap, got %q", got)
+	}
+
+	// A rune that opens nothing replaces the selection as before
+	typeKeys(e, "z")
+	if got := e.buffer.GetCurrentLine(); got != "call ([z]) now" {
+		t.Errorf("Expected the selection replaced, got %q", got)
+	}
+}
+
+func TestEnterExpandsBracketPair(t *testing.T) {
+	e := newTestEditor(t, "a.go", "func f() |", config.DefaultConfig)
+	typeKeys(e, "{")
+	e.handleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+
+	want := []string{"func f() {", "    ", "}"}
+	if !reflect.DeepEqual(e.buffer.Lines, want) {
+		t.Errorf("Expected %q, got %q", want, e.buffer.Lines)
+	}
+	if e.buffer.CursorX != 4 || e.buffer.CursorY != 1 {
+		t.Errorf("Expected the cursor on the indented middle line, got (%d, %d)", e.buffer.CursorX, e.buffer.CursorY)
+	}
+}
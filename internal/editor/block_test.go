@@ -0,0 +1,62 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+)
+
+func TestZeroWidthBlockDeleteSkipsShortLines(t *testing.T) {
+	for _, key := range []tcell.Key{tcell.KeyBackspace2, tcell.KeyDelete} {
+		e := newTestEditor(t, "a.txt", "abc|de\na\nabcde", config.DefaultConfig)
+		e.buffer.Modified = false
+		e.buffer.StartBlock()
+		e.buffer.MoveBlock(0, 2)
+
+		e.handleEvent(tcell.NewEventKey(key, 0, tcell.ModNone))
+
+		want := []string{"abde", "a", "abde"}
+		if key == tcell.KeyDelete {
+			want = []string{"abce", "a", "abce"}
+		}
+		if !reflect.DeepEqual(e.buffer.Lines, want) {
+			t.Errorf("Expected %q, got %q", want, e.buffer.Lines)
+		}
+	}
+
+	// Nothing to delete on any row leaves the buffer unmodified
+	e := newTestEditor(t, "a.txt", "abc|\na\nabc", config.DefaultConfig)
+	e.buffer.Modified = false
+	e.buffer.StartBlock()
+	e.buffer.MoveBlock(0, 2)
+	e.handleEvent(tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModNone))
+	if e.buffer.Lines[1] != "a" || e.buffer.Modified {
+		t.Errorf("Expected no padding, got %q (modified %v)", e.buffer.Lines, e.buffer.Modified)
+	}
+}
+
+func TestElectricCloseOnlyForOneCursor(t *testing.T) {
+	e := newTestEditor(t, "a.go", "\t\t|\n\t\t", config.DefaultConfig)
+	typeKeys(e, "}")
+	if got := e.buffer.Lines[0]; got != "\t}" {
+		t.Errorf("Expected one cursor to outdent, got %q", got)
+	}
+
+	e = newTestEditor(t, "a.go", "\t\t|\n\t\t", config.DefaultConfig)
+	e.buffer.StartBlock()
+	e.buffer.MoveBlock(0, 1)
+	typeKeys(e, "}")
+	if want := []string{"\t\t}", "\t\t}"}; !reflect.DeepEqual(e.buffer.Lines, want) {
+		t.Errorf("Expected a block to keep its indentation, got %q", e.buffer.Lines)
+	}
+
+	e = newTestEditor(t, "a.go", "\t\t|\n\t\t", config.DefaultConfig)
+	e.buffer.SetCursors([]buffer.Cursor{{CursorX: 2, CursorY: 0}, {CursorX: 2, CursorY: 1}})
+	typeKeys(e, "]")
+	if want := []string{"\t\t]", "\t\t]"}; !reflect.DeepEqual(e.buffer.Lines, want) {
+		t.Errorf("Expected multiple cursors to keep their indentation, got %q", e.buffer.Lines)
+	}
+}
@@ -4,14 +4,17 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
-	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell/v2"
 	"github.com/justynroberts/finpup/internal/ai"
 	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/clipboard"
 	"github.com/justynroberts/finpup/internal/config"
 	"github.com/justynroberts/finpup/internal/highlight"
+	"github.com/justynroberts/finpup/internal/killring"
 	"github.com/justynroberts/finpup/internal/ui"
+	"github.com/justynroberts/finpup/pkg/themes"
 )
 
 type Editor struct {
@@ -19,12 +22,32 @@ type Editor struct {
 	ui           *ui.UI
 	config       *config.Config
 	aiClient     *ai.Client
-	clipboard    string
+	clip         clipboard.Backend
+	killRing     *killring.Ring
+	registers    *killring.Registers
+	// lastPaste is set right after a paste so Alt+Y can swap in an older
+	// kill ring entry; any other key clears it.
+	lastPaste *pasteState
+	// pasting is true between the start and end of a bracketed paste,
+	// while the pasted keystrokes are collected in pasteBuf.
+	pasting  bool
+	pasteBuf strings.Builder
+	mouse    mouseState
 	running      bool
 	undoStack    [][]string
 	aiPromptHistory []string
 	lastAIPrompt string
 	insertMode   bool
+	// transientSelection is set for selections made with Shift+motion or
+	// the select commands; an unshifted motion drops them. Ctrl+W
+	// selection mode is sticky and ignores this.
+	transientSelection bool
+	// expandHistory holds the selections replaced by syntax expansion, so
+	// shrinking can restore them while the selection is still expanded.
+	expandHistory []buffer.Cursor
+	expanded      buffer.Cursor
+	// editorConfig lists the .editorconfig files applied to the buffer.
+	editorConfig []string
 }
 
 func New(filePath string) (*Editor, error) {
@@ -37,23 +60,42 @@ func New(filePath string) (*Editor, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create buffer: %w", err)
 	}
+	editorConfig, settingsErr := applySettings(buf, cfg)
 
-	ui, err := ui.New(buf)
+	// User themes must be registered before the UI looks up the current one
+	_, themeErrs := themes.LoadDir(themes.UserDir())
+
+	ui, err := ui.New(buf, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create UI: %w", err)
 	}
+	if len(themeErrs) > 0 {
+		ui.SetStatus(fmt.Sprintf("Theme error: %v", themeErrs[0]))
+	}
+	if settingsErr != nil {
+		ui.SetStatus(fmt.Sprintf("EditorConfig error: %v", settingsErr))
+	}
+
+	clip, err := clipboard.New(cfg.Clipboard.Backend, ui.Tty())
+	if err != nil {
+		ui.Close()
+		return nil, fmt.Errorf("failed to set up clipboard: %w", err)
+	}
 
 	return &Editor{
 		buffer:       buf,
 		ui:           ui,
 		config:       cfg,
 		aiClient:     ai.New(&cfg.AI),
-		clipboard:    "",
+		clip:         clip,
+		killRing:     killring.New(killRingSize),
+		registers:    killring.NewRegisters(),
 		running:      true,
 		undoStack:    make([][]string, 0, 50),
 		aiPromptHistory: make([]string, 0, 20),
 		lastAIPrompt: "",
 		insertMode:   true,
+		editorConfig: editorConfig,
 	}, nil
 }
 
@@ -65,7 +107,9 @@ func (e *Editor) Run() error {
 	for e.running {
 		ev := e.ui.PollEvent()
 		e.handleEvent(ev)
-		e.ui.Draw()
+		if !e.pasting {
+			e.ui.Draw()
+		}
 	}
 
 	return nil
@@ -76,7 +120,27 @@ func (e *Editor) handleEvent(ev tcell.Event) {
 	case *tcell.EventResize:
 		e.ui.Draw()
 
+	case *tcell.EventPaste:
+		e.handleBracketedPaste(ev)
+
+	case *tcell.EventMouse:
+		e.handleMouse(ev)
+
 	case *tcell.EventKey:
+		if e.pasting {
+			e.collectPasteKey(ev)
+			return
+		}
+
+		if !isPastePrevious(ev) {
+			e.lastPaste = nil
+		}
+		e.ui.FollowCursor()
+
+		if e.buffer.Block != nil && e.handleBlockKey(ev) {
+			return
+		}
+
 		if ev.Key() == tcell.KeyCtrlS {
 			e.handleSave()
 		} else if ev.Key() == tcell.KeyCtrlQ {
@@ -107,37 +171,79 @@ func (e *Editor) handleEvent(ev tcell.Event) {
 			e.handleJumpToTop()
 		} else if ev.Key() == tcell.KeyCtrlB {
 			e.handleJumpToBottom()
-		} else if ev.Key() == tcell.KeyCtrlI {
+		} else if ev.Key() == tcell.KeyCtrlL {
+			e.handleSelectLine()
+		} else if ev.Key() == tcell.KeyCtrlN {
+			e.handleAddNextOccurrence()
+		} else if ev.Key() == tcell.KeyEscape {
+			e.handleEscape()
+		} else if (ev.Key() == tcell.KeyUp || ev.Key() == tcell.KeyDown) && ev.Modifiers()&tcell.ModAlt != 0 {
+			e.handleAddCursor(ev.Key())
+		} else if ev.Key() == tcell.KeyRune && ev.Modifiers()&tcell.ModAlt != 0 {
+			e.handleAltRune(ev.Rune())
+		} else if ev.Key() == tcell.KeyTab {
+			// Ctrl+I arrives as Tab too, so insert mode is on the Insert key
+			e.saveUndo()
+			e.buffer.ForEachCursor(func() {
+				if e.buffer.HasSelection() {
+					e.buffer.IndentSelection(e.buffer.IndentUnit())
+				} else {
+					e.buffer.InsertTab()
+				}
+			})
+		} else if ev.Key() == tcell.KeyBacktab {
+			e.saveUndo()
+			e.buffer.ForEachCursor(func() { e.buffer.OutdentSelection(e.buffer.IndentWidth()) })
+		} else if ev.Key() == tcell.KeyInsert {
 			e.toggleInsertMode()
 		} else if ev.Key() == tcell.KeyEnter {
 			e.saveUndo()
-			e.buffer.InsertNewline()
+			e.buffer.ClearBlock()
+			e.buffer.ForEachCursor(func() {
+				e.deleteSelectionForEdit()
+				e.insertNewline()
+			})
 		} else if ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2 {
 			e.saveUndo()
-			e.buffer.DeleteRune()
+			e.buffer.ForEachCursor(func() {
+				if !e.deleteSelectionForEdit() && !e.deletePair() {
+					e.buffer.DeleteRune()
+				}
+			})
+		} else if ev.Key() == tcell.KeyDelete {
+			e.saveUndo()
+			e.buffer.ForEachCursor(func() {
+				if !e.deleteSelectionForEdit() {
+					e.buffer.DeleteForward()
+				}
+			})
 		} else if ev.Key() == tcell.KeyUp {
-			e.moveCursorUp()
+			e.withSelection(ev, e.moveCursorUp)
 		} else if ev.Key() == tcell.KeyDown {
-			e.moveCursorDown()
+			e.withSelection(ev, e.moveCursorDown)
 		} else if ev.Key() == tcell.KeyLeft {
-			e.moveCursorLeft()
+			if ev.Modifiers()&tcell.ModCtrl != 0 {
+				e.withSelection(ev, e.moveWordLeft)
+			} else {
+				e.withSelection(ev, e.moveCursorLeft)
+			}
 		} else if ev.Key() == tcell.KeyRight {
-			e.moveCursorRight()
+			if ev.Modifiers()&tcell.ModCtrl != 0 {
+				e.withSelection(ev, e.moveWordRight)
+			} else {
+				e.withSelection(ev, e.moveCursorRight)
+			}
 		} else if ev.Key() == tcell.KeyHome {
-			e.buffer.CursorX = 0
+			e.withSelection(ev, func() { e.buffer.CursorX = 0 })
 		} else if ev.Key() == tcell.KeyEnd {
-			e.buffer.CursorX = len(e.buffer.GetCurrentLine())
+			e.withSelection(ev, func() { e.buffer.CursorX = len(e.buffer.GetCurrentLine()) })
 		} else if ev.Key() == tcell.KeyPgUp {
-			e.pageUp()
+			e.withSelection(ev, e.pageUp)
 		} else if ev.Key() == tcell.KeyPgDn {
-			e.pageDown()
+			e.withSelection(ev, e.pageDown)
 		} else if ev.Key() == tcell.KeyRune {
 			e.saveUndo()
-			if e.insertMode {
-				e.buffer.InsertRune(ev.Rune())
-			} else {
-				e.buffer.OverwriteRune(ev.Rune())
-			}
+			e.buffer.ForEachCursor(func() { e.typeRune(ev.Rune()) })
 		}
 	}
 }
@@ -150,6 +256,12 @@ func (e *Editor) handleSave() {
 			return
 		}
 		e.buffer.FilePath = prompt
+		e.ui.DetectLanguage()
+		editorConfig, err := applySettings(e.buffer, e.config)
+		if err != nil {
+			e.ui.SetStatus(fmt.Sprintf("EditorConfig error: %v", err))
+		}
+		e.editorConfig = editorConfig
 	}
 
 	if err := e.buffer.Save(); err != nil {
@@ -171,12 +283,25 @@ func (e *Editor) handleQuit() {
 }
 
 func (e *Editor) handleCopy() {
-	line := e.buffer.GetCurrentLine()
-	e.clipboard = line
+	if e.buffer.Block != nil {
+		e.copyBlock()
+		return
+	}
+
+	var parts []string
+	e.buffer.ForEachCursor(func() {
+		if e.buffer.HasSelection() {
+			parts = append(parts, e.buffer.GetSelection())
+		} else {
+			parts = append(parts, e.buffer.GetCurrentLine())
+		}
+	})
+	line := strings.Join(parts, "\n")
+	e.killRing.Push(killring.Entry{Text: line})
 
 	// Try to copy to system clipboard
-	if err := clipboard.WriteAll(line); err == nil {
-		e.ui.SetStatus("Copied to clipboard")
+	if err := e.clip.Write(line); err == nil {
+		e.ui.SetStatus(fmt.Sprintf("Copied to clipboard (%s)", e.clip.Name()))
 	} else {
 		e.ui.SetStatus("Copied to internal clipboard")
 	}
@@ -184,26 +309,65 @@ func (e *Editor) handleCopy() {
 
 func (e *Editor) handlePaste() {
 	// Try system clipboard first
-	text, err := clipboard.ReadAll()
-	if err == nil && text != "" {
-		e.buffer.InsertText(text)
+	text, err := e.clip.Read()
+	latest, ok := e.killRing.Latest()
+	if err == nil && text != "" && (!ok || text != latest.Text) {
+		// Copied from another application; remember it like our own copies
+		e.killRing.Push(killring.Entry{Text: text})
+		e.saveUndo()
+		e.pasteEntry(0)
 		e.ui.SetStatus("Pasted from clipboard")
 		return
 	}
 
 	// Fall back to internal clipboard
-	if e.clipboard != "" {
-		e.buffer.InsertText(e.clipboard)
+	if ok {
+		e.saveUndo()
+		e.pasteEntry(0)
 		e.ui.SetStatus("Pasted from internal clipboard")
 	}
 }
 
+// pasteText inserts text at every cursor. When the text has exactly one
+// line per cursor, each cursor gets its own line instead.
+func (e *Editor) pasteText(text string) {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	split := e.buffer.HasMultipleCursors() && len(lines) == e.buffer.CursorCount()
+
+	i := 0
+	e.buffer.ForEachCursor(func() {
+		e.deleteSelectionForEdit()
+		if split {
+			e.buffer.InsertText(lines[i])
+		} else {
+			e.buffer.InsertText(text)
+		}
+		i++
+	})
+}
+
 func (e *Editor) handleCut() {
-	line := e.buffer.DeleteCurrentLine()
-	e.clipboard = line
+	e.saveUndo()
+	if e.buffer.Block != nil {
+		e.copyBlock()
+		e.buffer.DeleteBlock()
+		return
+	}
+
+	var parts []string
+	e.buffer.ForEachCursor(func() {
+		if e.buffer.HasSelection() {
+			parts = append(parts, e.buffer.GetSelection())
+			e.deleteSelectionForEdit()
+		} else {
+			parts = append(parts, e.buffer.DeleteCurrentLine())
+		}
+	})
+	line := strings.Join(parts, "\n")
+	e.killRing.Push(killring.Entry{Text: line})
 
-	if err := clipboard.WriteAll(line); err == nil {
-		e.ui.SetStatus("Cut to clipboard")
+	if err := e.clip.Write(line); err == nil {
+		e.ui.SetStatus(fmt.Sprintf("Cut to clipboard (%s)", e.clip.Name()))
 	} else {
 		e.ui.SetStatus("Cut to internal clipboard")
 	}
@@ -255,6 +419,11 @@ func (e *Editor) handleAI() {
 	e.ui.SetStatus("Generating AI response...")
 	e.ui.Draw()
 
+	if mode == "replace" && e.buffer.HasMultipleCursors() {
+		e.handleAIReplaceAtCursors(prompt)
+		return
+	}
+
 	result, err := e.aiClient.GenerateText(prompt, context)
 	if err != nil {
 		e.ui.SetStatus(fmt.Sprintf("AI error: %v", err))
@@ -270,6 +439,7 @@ func (e *Editor) handleAI() {
 			e.ui.SetStatus("Selection replaced with AI response")
 		} else {
 			// Replace entire buffer
+			e.resetCursors()
 			e.buffer.Lines = strings.Split(result, "\n")
 			e.buffer.CursorY = 0
 			e.buffer.CursorX = 0
@@ -278,6 +448,7 @@ func (e *Editor) handleAI() {
 		}
 	case "overwrite":
 		// Clear entire buffer and insert AI response
+		e.resetCursors()
 		e.buffer.Lines = strings.Split(result, "\n")
 		e.buffer.CursorY = 0
 		e.buffer.CursorX = 0
@@ -289,7 +460,35 @@ func (e *Editor) handleAI() {
 	}
 
 	// Clear selection after AI operation
-	e.buffer.SelectMode = false
+	e.clearSelection()
+}
+
+// handleAIReplaceAtCursors sends each cursor's selection to the AI
+// separately and replaces it with the response.
+func (e *Editor) handleAIReplaceAtCursors(prompt string) {
+	e.saveUndo()
+
+	var firstErr error
+	replaced := 0
+	e.buffer.ForEachCursor(func() {
+		if !e.buffer.HasSelection() || firstErr != nil {
+			return
+		}
+		result, err := e.aiClient.GenerateText(prompt, e.buffer.GetSelection())
+		if err != nil {
+			firstErr = err
+			return
+		}
+		e.buffer.ReplaceSelection(result)
+		replaced++
+	})
+	e.transientSelection = false
+
+	if firstErr != nil {
+		e.ui.SetStatus(fmt.Sprintf("AI error after %d replacements: %v", replaced, firstErr))
+		return
+	}
+	e.ui.SetStatus(fmt.Sprintf("Replaced %d selections with AI responses", replaced))
 }
 
 func (e *Editor) handleEmojiPicker() {
@@ -341,20 +540,31 @@ func (e *Editor) handleFormat() {
 	}
 
 	lines := strings.Split(formatted, "\n")
+	e.resetCursors()
 	e.buffer.Lines = lines
 	e.buffer.Modified = true
 }
 
 func (e *Editor) moveCursorUp() {
+	if e.ui.SoftWrap() {
+		// Move by screen rows so wrapped lines can be walked through
+		e.buffer.CursorX, e.buffer.CursorY = e.ui.VisualMove(e.buffer.CursorX, e.buffer.CursorY, -1)
+		return
+	}
+	// Lines inside closed folds are stepped over
 	if e.buffer.CursorY > 0 {
-		e.buffer.CursorY--
+		e.buffer.CursorY = e.ui.PrevLine(e.buffer.CursorY)
 		e.adjustCursorX()
 	}
 }
 
 func (e *Editor) moveCursorDown() {
+	if e.ui.SoftWrap() {
+		e.buffer.CursorX, e.buffer.CursorY = e.ui.VisualMove(e.buffer.CursorX, e.buffer.CursorY, 1)
+		return
+	}
 	if e.buffer.CursorY < len(e.buffer.Lines)-1 {
-		e.buffer.CursorY++
+		e.buffer.CursorY = e.ui.NextLine(e.buffer.CursorY)
 		e.adjustCursorX()
 	}
 }
@@ -363,7 +573,7 @@ func (e *Editor) moveCursorLeft() {
 	if e.buffer.CursorX > 0 {
 		e.buffer.CursorX--
 	} else if e.buffer.CursorY > 0 {
-		e.buffer.CursorY--
+		e.buffer.CursorY = e.ui.PrevLine(e.buffer.CursorY)
 		e.buffer.CursorX = len(e.buffer.GetCurrentLine())
 	}
 }
@@ -372,12 +582,64 @@ func (e *Editor) moveCursorRight() {
 	lineLen := len(e.buffer.GetCurrentLine())
 	if e.buffer.CursorX < lineLen {
 		e.buffer.CursorX++
-	} else if e.buffer.CursorY < len(e.buffer.Lines)-1 {
-		e.buffer.CursorY++
+	} else if next := e.ui.NextLine(e.buffer.CursorY); next != e.buffer.CursorY {
+		e.buffer.CursorY = next
 		e.buffer.CursorX = 0
 	}
 }
 
+func (e *Editor) moveWordLeft() {
+	line := e.buffer.GetCurrentLine()
+	x := e.buffer.CursorX
+	if x == 0 {
+		e.moveCursorLeft()
+		return
+	}
+
+	// Skip separators, then the word itself
+	for x > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:x])
+		if buffer.IsWordRune(r) {
+			break
+		}
+		x -= size
+	}
+	for x > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:x])
+		if !buffer.IsWordRune(r) {
+			break
+		}
+		x -= size
+	}
+	e.buffer.CursorX = x
+}
+
+func (e *Editor) moveWordRight() {
+	line := e.buffer.GetCurrentLine()
+	x := e.buffer.CursorX
+	if x >= len(line) {
+		e.moveCursorRight()
+		return
+	}
+
+	// Skip the current word, then separators up to the next one
+	for x < len(line) {
+		r, size := utf8.DecodeRuneInString(line[x:])
+		if !buffer.IsWordRune(r) {
+			break
+		}
+		x += size
+	}
+	for x < len(line) {
+		r, size := utf8.DecodeRuneInString(line[x:])
+		if buffer.IsWordRune(r) {
+			break
+		}
+		x += size
+	}
+	e.buffer.CursorX = x
+}
+
 func (e *Editor) adjustCursorX() {
 	lineLen := len(e.buffer.GetCurrentLine())
 	if e.buffer.CursorX > lineLen {
@@ -385,26 +647,32 @@ func (e *Editor) adjustCursorX() {
 	}
 }
 
+// pageUp and pageDown move ten visible lines, not counting those inside
+// closed folds.
 func (e *Editor) pageUp() {
-	e.buffer.CursorY -= 10
-	if e.buffer.CursorY < 0 {
-		e.buffer.CursorY = 0
+	for i := 0; i < 10; i++ {
+		e.buffer.CursorY = e.ui.PrevLine(e.buffer.CursorY)
 	}
 	e.adjustCursorX()
 }
 
 func (e *Editor) pageDown() {
-	e.buffer.CursorY += 10
-	if e.buffer.CursorY >= len(e.buffer.Lines) {
-		e.buffer.CursorY = len(e.buffer.Lines) - 1
+	for i := 0; i < 10; i++ {
+		e.buffer.CursorY = e.ui.NextLine(e.buffer.CursorY)
 	}
 	e.adjustCursorX()
 }
 
 func (e *Editor) handleDeleteLine() {
 	e.saveUndo()
+	if e.buffer.HasSelection() {
+		e.killRing.Push(killring.Entry{Text: e.buffer.GetSelection()})
+		e.deleteSelectionForEdit()
+		e.ui.SetStatus("Selection deleted (in clipboard)")
+		return
+	}
 	line := e.buffer.DeleteCurrentLine()
-	e.clipboard = line
+	e.killRing.Push(killring.Entry{Text: line})
 	e.ui.SetStatus("Line deleted (in clipboard)")
 }
 
@@ -428,6 +696,7 @@ func (e *Editor) handleGoToLine() {
 		lineNum = len(e.buffer.Lines) - 1
 	}
 
+	e.resetCursors()
 	e.buffer.CursorY = lineNum
 	e.buffer.CursorX = 0
 	e.ui.SetStatus(fmt.Sprintf("Jumped to line %d", lineNum+1))
@@ -458,6 +727,7 @@ func (e *Editor) handleUndo() {
 
 	e.buffer.Lines = lastState
 	e.buffer.Modified = true
+	e.resetCursors()
 
 	// Adjust cursor if needed
 	if e.buffer.CursorY >= len(e.buffer.Lines) {
@@ -469,12 +739,14 @@ func (e *Editor) handleUndo() {
 }
 
 func (e *Editor) handleJumpToTop() {
+	e.resetCursors()
 	e.buffer.CursorY = 0
 	e.buffer.CursorX = 0
 	e.ui.SetStatus("Jumped to top")
 }
 
 func (e *Editor) handleJumpToBottom() {
+	e.resetCursors()
 	e.buffer.CursorY = len(e.buffer.Lines) - 1
 	e.buffer.CursorX = 0
 	e.ui.SetStatus("Jumped to bottom")
@@ -491,9 +763,355 @@ func (e *Editor) toggleInsertMode() {
 
 func (e *Editor) handleToggleSelection() {
 	e.buffer.ToggleSelection()
+	e.transientSelection = false
 	if e.buffer.SelectMode {
 		e.ui.SetStatus("Selection mode ON - move cursor to select")
 	} else {
 		e.ui.SetStatus("Selection mode OFF")
 	}
 }
+
+// withSelection runs a cursor motion at every cursor, extending the
+// selection while Shift is held and dropping a Shift selection when it is not.
+func (e *Editor) withSelection(ev *tcell.EventKey, move func()) {
+	shift := ev.Modifiers()&tcell.ModShift != 0
+	transient := e.transientSelection
+	e.buffer.ForEachCursor(func() {
+		if shift {
+			if !e.buffer.SelectMode {
+				e.buffer.StartSelection()
+				transient = true
+			}
+		} else if transient {
+			e.buffer.ClearSelection()
+		}
+		move()
+	})
+	e.transientSelection = shift && transient
+}
+
+// typeRune inserts r at the current cursor, replacing any selection.
+func (e *Editor) typeRune(r rune) {
+	if e.buffer.HasSelection() {
+		if !e.wrapSelection(r) {
+			e.buffer.ReplaceSelection(string(r))
+			e.transientSelection = false
+		}
+	} else if e.insertMode {
+		e.clearSelection()
+		if e.overtype(r) {
+			return
+		}
+		e.electricClose(r)
+		if !e.autoClose(r) {
+			e.buffer.InsertRune(r)
+		}
+	} else {
+		e.clearSelection()
+		e.buffer.OverwriteRune(r)
+	}
+}
+
+func (e *Editor) clearSelection() {
+	e.buffer.ClearSelection()
+	e.transientSelection = false
+}
+
+// deleteSelectionForEdit removes the selected text ahead of an edit that
+// replaces it, and ends selection mode either way.
+func (e *Editor) deleteSelectionForEdit() bool {
+	deleted := e.buffer.HasSelection()
+	if deleted {
+		e.buffer.DeleteSelection()
+	}
+	e.clearSelection()
+	return deleted
+}
+
+func (e *Editor) handleAltRune(r rune) {
+	switch r {
+	case 'w':
+		if e.buffer.SelectWord() {
+			e.transientSelection = true
+		}
+	case 'b':
+		e.handleToggleBlock()
+	case 'v':
+		e.handlePasteBlock()
+	case 't':
+		e.handleCycleTheme()
+	case 'y':
+		e.handlePastePrevious()
+	case 'h':
+		e.handleClipboardHistory()
+	case 'r':
+		e.handleYankToRegister()
+	case 'p':
+		e.handlePasteFromRegister()
+	case 'l':
+		if e.buffer.SplitSelectionIntoLines() {
+			e.transientSelection = true
+			e.ui.SetStatus(fmt.Sprintf("%d cursors", e.buffer.CursorCount()))
+		}
+	case 'a':
+		e.buffer.ClearExtraCursors()
+		e.buffer.SelectAll()
+		e.transientSelection = true
+		e.ui.SetStatus("Selected all")
+	case 'o':
+		e.handleExpandSelection()
+	case 'i':
+		e.handleShrinkSelection()
+	case 'u':
+		e.handleSyntaxParent()
+	case 'n':
+		e.handleNextSibling()
+	case 'm':
+		e.handleSetLanguage()
+	case 'f':
+		e.toggleFold(e.buffer.CursorY)
+	case 'c':
+		e.handleFoldToLevel(0)
+	case 'e':
+		e.ui.UnfoldAll()
+		e.ui.SetStatus("Unfolded all")
+	case 'g':
+		e.handleFoldLevelPrompt()
+	case 'd':
+		e.handleFileInfo()
+	case 'j':
+		e.handleReindent()
+	case 's':
+		if e.ui.ToggleWhitespace() {
+			e.ui.SetStatus("Whitespace shown")
+		} else {
+			e.ui.SetStatus("Whitespace hidden")
+		}
+	case 'z':
+		if e.ui.ToggleSoftWrap() {
+			e.ui.SetStatus("Soft wrap on")
+		} else {
+			e.ui.SetStatus("Soft wrap off")
+		}
+	}
+}
+
+// handleSetLanguage asks which language to highlight the buffer as;
+// "auto" goes back to detecting it.
+func (e *Editor) handleSetLanguage() {
+	input, ok := e.ui.ShowPrompt("Language: ")
+	input = strings.TrimSpace(input)
+	if !ok || input == "" {
+		return
+	}
+
+	if strings.EqualFold(input, "auto") {
+		input = ""
+	}
+	if !e.ui.SetLanguage(input) {
+		e.ui.SetStatus(fmt.Sprintf("Unknown language: %s", input))
+		return
+	}
+
+	language := e.ui.Language()
+	if language == "" {
+		language = "plain text"
+	}
+	e.ui.SetStatus(fmt.Sprintf("Language: %s", language))
+}
+
+// handleCycleTheme switches to the next theme and remembers it in the
+// config file.
+func (e *Editor) handleCycleTheme() {
+	theme := themes.NextTheme(e.config.Theme.Current)
+	e.config.Theme.Current = theme.Name
+	e.ui.SetTheme(theme)
+
+	if err := config.Save(e.config); err != nil {
+		e.ui.SetStatus(fmt.Sprintf("Theme: %s (not saved: %v)", theme.Name, err))
+		return
+	}
+	e.ui.SetStatus(fmt.Sprintf("Theme: %s", theme.Name))
+}
+
+func (e *Editor) handleSelectLine() {
+	e.buffer.SelectLine()
+	e.transientSelection = true
+}
+
+func (e *Editor) handleAddNextOccurrence() {
+	if !e.buffer.AddNextOccurrence() {
+		e.ui.SetStatus("No more occurrences")
+		return
+	}
+	e.transientSelection = true
+	if e.buffer.HasMultipleCursors() {
+		e.ui.SetStatus(fmt.Sprintf("%d cursors", e.buffer.CursorCount()))
+	}
+}
+
+func (e *Editor) handleAddCursor(key tcell.Key) {
+	dir := 1
+	if key == tcell.KeyUp {
+		dir = -1
+	}
+	if e.buffer.AddCursorVertical(dir) {
+		e.ui.SetStatus(fmt.Sprintf("%d cursors", e.buffer.CursorCount()))
+	}
+}
+
+// handleEscape collapses multiple cursors, then clears the selection.
+func (e *Editor) handleEscape() {
+	if e.buffer.HasMultipleCursors() {
+		e.buffer.ClearExtraCursors()
+		e.ui.SetStatus("Single cursor")
+		return
+	}
+	e.clearSelection()
+}
+
+// resetCursors drops extra cursors and any block selection, used when the
+// buffer contents are replaced wholesale or the cursor jumps.
+func (e *Editor) resetCursors() {
+	e.buffer.ClearExtraCursors()
+	e.buffer.ClearBlock()
+}
+
+func (e *Editor) handleToggleBlock() {
+	if e.buffer.Block != nil {
+		e.buffer.ClearBlock()
+		e.ui.SetStatus("Block selection OFF")
+		return
+	}
+	e.buffer.StartBlock()
+	e.transientSelection = false
+	e.ui.SetStatus("Block selection ON - arrows resize, type to edit every row")
+}
+
+// handleBlockKey handles keys that behave differently while a block
+// selection is active. It reports whether the key was consumed.
+func (e *Editor) handleBlockKey(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyUp:
+		e.buffer.MoveBlock(0, -1)
+	case tcell.KeyDown:
+		e.buffer.MoveBlock(0, 1)
+	case tcell.KeyLeft:
+		e.buffer.MoveBlock(-1, 0)
+	case tcell.KeyRight:
+		e.buffer.MoveBlock(1, 0)
+	case tcell.KeyEscape:
+		e.buffer.ClearBlock()
+		e.ui.SetStatus("Block selection OFF")
+	case tcell.KeyBackspace, tcell.KeyBackspace2, tcell.KeyDelete:
+		e.saveUndo()
+		startCol, _, endCol, _ := e.buffer.BlockBounds()
+		if startCol != endCol {
+			e.buffer.DeleteBlock()
+			return true
+		}
+		// A zero-width block deletes one character on every row without
+		// joining lines. Lines that end before its column are left alone.
+		forward := ev.Key() == tcell.KeyDelete
+		e.buffer.BlockToCursors(false)
+		e.buffer.ForEachCursor(func() {
+			line := e.buffer.GetCurrentLine()
+			if buffer.LineWidth(line, e.buffer.TabStop()) < startCol {
+				return
+			}
+			if forward && e.buffer.CursorX < len(line) {
+				e.buffer.DeleteForward()
+			} else if !forward && e.buffer.CursorX > 0 {
+				e.buffer.DeleteRune()
+			}
+		})
+	case tcell.KeyRune:
+		if ev.Modifiers()&tcell.ModAlt != 0 {
+			return false
+		}
+		e.saveUndo()
+		e.buffer.DeleteBlock()
+		e.buffer.BlockToCursors(true)
+		e.buffer.ForEachCursor(func() { e.typeRune(ev.Rune()) })
+	default:
+		return false
+	}
+	return true
+}
+
+func (e *Editor) copyBlock() {
+	text := strings.Join(e.buffer.BlockText(), "\n")
+	e.killRing.Push(killring.Entry{Text: text, Block: true})
+
+	if err := e.clip.Write(text); err == nil {
+		e.ui.SetStatus(fmt.Sprintf("Block copied to clipboard (%s)", e.clip.Name()))
+	} else {
+		e.ui.SetStatus("Block copied to internal clipboard")
+	}
+}
+
+func (e *Editor) pasteBlock(text string) {
+	e.resetCursors()
+	e.deleteSelectionForEdit()
+	e.buffer.InsertBlock(strings.Split(text, "\n"))
+}
+
+// handlePasteBlock pastes the clipboard as a rectangle regardless of how
+// it was copied.
+func (e *Editor) handlePasteBlock() {
+	text, err := e.clip.Read()
+	if err != nil || text == "" {
+		latest, _ := e.killRing.Latest()
+		text = latest.Text
+	}
+	if text == "" {
+		return
+	}
+
+	e.saveUndo()
+	e.pasteBlock(text)
+	e.ui.SetStatus("Pasted block")
+}
+
+func (e *Editor) handleBracketedPaste(ev *tcell.EventPaste) {
+	if ev.Start() {
+		e.pasting = true
+		e.pasteBuf.Reset()
+		return
+	}
+
+	e.pasting = false
+	text := e.pasteBuf.String()
+	e.pasteBuf.Reset()
+
+	// Terminals send line breaks as CR, sometimes followed by LF
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if text == "" {
+		return
+	}
+
+	// Insert verbatim as a single undo step, bypassing per-key behaviour
+	e.saveUndo()
+	e.buffer.ClearBlock()
+	e.pasteText(text)
+	if e.buffer.FilePath == "" {
+		// A new buffer has nothing but its content to go on
+		e.ui.DetectLanguage()
+	}
+	e.ui.SetStatus(fmt.Sprintf("Pasted %d lines", strings.Count(text, "\n")+1))
+}
+
+// collectPasteKey records a keystroke that arrived inside a bracketed paste.
+func (e *Editor) collectPasteKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyRune:
+		e.pasteBuf.WriteRune(ev.Rune())
+	case tcell.KeyEnter:
+		e.pasteBuf.WriteByte('\r')
+	case tcell.KeyCtrlJ:
+		e.pasteBuf.WriteByte('\n')
+	case tcell.KeyTab:
+		e.pasteBuf.WriteByte('\t')
+	}
+}
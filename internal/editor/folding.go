@@ -0,0 +1,66 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// toggleFold opens or closes the fold at line y. A cursor that the fold
+// would hide moves to its first line.
+func (e *Editor) toggleFold(y int) {
+	r, ok := e.ui.ToggleFold(y)
+	if !ok {
+		e.ui.SetStatus("Nothing to fold here")
+		return
+	}
+	if e.ui.Folded(r.Start) {
+		e.ui.SetStatus(fmt.Sprintf("Folded %s", lineCount(r.End-r.Start)))
+	} else {
+		e.ui.SetStatus(fmt.Sprintf("Unfolded %s", lineCount(r.End-r.Start)))
+	}
+	e.keepCursorVisible()
+}
+
+// handleFoldToLevel folds every region nested deeper than level.
+func (e *Editor) handleFoldToLevel(level int) {
+	n := e.ui.FoldToLevel(level)
+	if n == 0 {
+		e.ui.SetStatus("Nothing to fold")
+		return
+	}
+	if level == 0 {
+		e.ui.SetStatus(fmt.Sprintf("Folded %d regions", n))
+	} else {
+		e.ui.SetStatus(fmt.Sprintf("Folded %d regions below level %d", n, level))
+	}
+	e.keepCursorVisible()
+}
+
+func (e *Editor) handleFoldLevelPrompt() {
+	input, ok := e.ui.ShowPrompt("Fold to level: ")
+	input = strings.TrimSpace(input)
+	if !ok || input == "" {
+		return
+	}
+	level, err := strconv.Atoi(input)
+	if err != nil || level < 0 {
+		e.ui.SetStatus(fmt.Sprintf("Invalid level: %s", input))
+		return
+	}
+	e.handleFoldToLevel(level)
+}
+
+// keepCursorVisible moves the cursor out of closed folds onto the fold's
+// first line; otherwise the next redraw would open the fold again.
+func (e *Editor) keepCursorVisible() {
+	e.buffer.CursorY = e.ui.VisibleLine(e.buffer.CursorY)
+	e.adjustCursorX()
+}
+
+func lineCount(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
@@ -0,0 +1,159 @@
+package editor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+)
+
+// indentRule says how indentation changes from one line to the next.
+type indentRule struct {
+	// openers end a line whose successor is indented one level deeper.
+	// Words only match whole.
+	openers []string
+	// dedenters start a line whose successor is one level shallower.
+	dedenters []string
+}
+
+var defaultIndentRule = indentRule{openers: []string{"{", "[", "("}}
+
+// indentRules are keyed by lower-case language name.
+var indentRules = map[string]indentRule{
+	"python": {
+		openers:   []string{"{", "[", "(", ":"},
+		dedenters: []string{"return", "pass", "break", "continue", "raise"},
+	},
+	"yaml":     {openers: []string{"{", "[", ":", "|", ">", "|-", ">-"}},
+	"makefile": {openers: []string{":"}},
+	"bash":     {openers: []string{"{", "(", "then", "do", "else", "in"}},
+	"lua":      {openers: []string{"{", "(", "then", "do", "else"}},
+	"ruby":     {openers: []string{"{", "[", "(", "do", "then", "else", "|"}},
+}
+
+func (e *Editor) indentRule() indentRule {
+	if rule, ok := indentRules[strings.ToLower(e.ui.Language())]; ok {
+		return rule
+	}
+	return defaultIndentRule
+}
+
+// opens reports whether text, a line with surrounding space trimmed, ends
+// with one of the rule's openers.
+func (r indentRule) opens(text string) bool {
+	for _, o := range r.openers {
+		if !strings.HasSuffix(text, o) {
+			continue
+		}
+		rest := strings.TrimSuffix(text, o)
+		if !isWord(o) || rest == "" || !buffer.IsWordRune(rune(rest[len(rest)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedents reports whether text starts with one of the rule's dedenters.
+func (r indentRule) dedents(text string) bool {
+	for _, d := range r.dedenters {
+		if text == d || strings.HasPrefix(text, d+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func isWord(s string) bool {
+	return s != "" && buffer.IsWordRune(rune(s[0]))
+}
+
+// insertNewline breaks the line at the cursor. With auto-indent the new
+// line copies the current one's indentation, a level deeper after an
+// opener such as "{" or ":" and a level shallower after e.g. "return".
+// Enter between a bracket pair opens it up into an indented block.
+func (e *Editor) insertNewline() {
+	if !e.config.Editor.AutoIndent {
+		e.buffer.InsertNewline()
+		return
+	}
+
+	line := e.buffer.GetCurrentLine()
+	before := line[:min(e.buffer.CursorX, len(line))]
+	indent := buffer.Indentation(before)
+	text := strings.TrimSpace(before)
+
+	// Between brackets, as in "{|}", the closer goes on a line of its own
+	// with the blank line between them indented a level deeper
+	if e.expandsBlock() {
+		e.buffer.InsertIndentedNewline(indent)
+		e.buffer.CursorY--
+		e.buffer.CursorX = len(e.buffer.GetCurrentLine())
+		e.buffer.InsertIndentedNewline(indent + e.buffer.IndentUnit())
+		return
+	}
+
+	rule := e.indentRule()
+	switch {
+	case rule.opens(text):
+		indent += e.buffer.IndentUnit()
+	case rule.dedents(text):
+		indent = e.outdent(indent)
+	}
+	e.buffer.InsertIndentedNewline(indent)
+}
+
+// outdent removes one level from the end of indent: a tab or up to a
+// level's worth of spaces.
+func (e *Editor) outdent(indent string) string {
+	if strings.HasSuffix(indent, "\t") {
+		return indent[:len(indent)-1]
+	}
+	n := 0
+	for n < e.buffer.IndentWidth() && n < len(indent) && indent[len(indent)-1-n] == ' ' {
+		n++
+	}
+	return indent[:len(indent)-n]
+}
+
+// electricClose outdents the current line when r, a closing bracket, is
+// typed as its first non-blank character, so "}" lines up with the line
+// that opened the block. Typing at several cursors or into a block is
+// column editing, so there every line keeps its indentation.
+func (e *Editor) electricClose(r rune) {
+	if !e.config.Editor.AutoIndent || !strings.ContainsRune("}])", r) || e.buffer.HasMultipleCursors() {
+		return
+	}
+	line := e.buffer.GetCurrentLine()
+	indent := buffer.Indentation(line)
+	if indent == "" || e.buffer.CursorX != len(indent) {
+		return
+	}
+	outdented := e.outdent(indent)
+	e.buffer.Lines[e.buffer.CursorY] = outdented + line[len(indent):]
+	e.buffer.CursorX = len(outdented)
+}
+
+// handleReindent converts the buffer's indentation to tabs or to a number
+// of spaces per level, and indents new lines that way from then on.
+func (e *Editor) handleReindent() {
+	input, ok := e.ui.ShowPrompt(fmt.Sprintf("Re-indent from %s to (tabs or width): ", e.buffer.IndentStyle()))
+	input = strings.ToLower(strings.TrimSpace(input))
+	if !ok || input == "" {
+		return
+	}
+
+	var style buffer.IndentStyle
+	if input == "tab" || input == "tabs" {
+		style.Tabs = true
+	} else if n, err := strconv.Atoi(input); err == nil && n > 0 && n <= 16 {
+		style.Width = n
+	} else {
+		e.ui.SetStatus(fmt.Sprintf("Invalid indentation: %s", input))
+		return
+	}
+
+	e.saveUndo()
+	n := e.buffer.Reindent(style)
+	e.ui.SetStatus(fmt.Sprintf("Re-indented %s with %s", lineCount(n), style))
+}
@@ -0,0 +1,149 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/killring"
+)
+
+const killRingSize = 30
+
+// pasteState remembers the most recent paste so it can be replaced by an
+// older kill ring entry.
+type pasteState struct {
+	index   int
+	cursors []buffer.Cursor
+}
+
+func isPastePrevious(ev *tcell.EventKey) bool {
+	return ev.Key() == tcell.KeyRune && ev.Modifiers()&tcell.ModAlt != 0 && ev.Rune() == 'y'
+}
+
+// pasteEntry pastes the i-th kill ring entry at every cursor. The caller is
+// responsible for saving undo state first.
+func (e *Editor) pasteEntry(i int) {
+	entry, ok := e.killRing.Get(i)
+	if !ok {
+		return
+	}
+
+	state := &pasteState{index: i, cursors: e.buffer.Cursors()}
+	e.insertEntry(entry)
+	e.lastPaste = state
+}
+
+func (e *Editor) insertEntry(entry killring.Entry) {
+	if entry.Block {
+		e.pasteBlock(entry.Text)
+	} else {
+		e.pasteText(entry.Text)
+	}
+}
+
+// handlePastePrevious replaces the text just pasted with the next older
+// kill ring entry, cycling back to the newest after the oldest.
+func (e *Editor) handlePastePrevious() {
+	if e.lastPaste == nil || len(e.undoStack) == 0 {
+		e.ui.SetStatus("Paste previous only works right after a paste")
+		return
+	}
+
+	// The undo snapshot taken before the paste is the pre-paste buffer
+	snapshot := e.undoStack[len(e.undoStack)-1]
+	e.buffer.Lines = append([]string(nil), snapshot...)
+	e.buffer.SetCursors(e.lastPaste.cursors)
+	e.buffer.ClearBlock()
+
+	next := (e.lastPaste.index + 1) % e.killRing.Len()
+	e.pasteEntry(next)
+	e.ui.SetStatus(fmt.Sprintf("Pasted kill ring entry %d/%d", next+1, e.killRing.Len()))
+}
+
+// handleClipboardHistory lets the user pick any kill ring entry to paste.
+func (e *Editor) handleClipboardHistory() {
+	entries := e.killRing.Entries()
+	if len(entries) == 0 {
+		e.ui.SetStatus("Clipboard history is empty")
+		return
+	}
+
+	items := make([]string, len(entries))
+	for i, entry := range entries {
+		items[i] = entryPreview(entry)
+	}
+
+	idx, ok := e.ui.ShowListPicker("Clipboard history (↑↓ navigate, Enter paste, Esc cancel)", items)
+	if !ok {
+		e.ui.SetStatus("Paste cancelled")
+		return
+	}
+
+	e.saveUndo()
+	e.pasteEntry(idx)
+	e.ui.SetStatus(fmt.Sprintf("Pasted kill ring entry %d/%d", idx+1, len(entries)))
+}
+
+// handleYankToRegister copies the selection, block or current line into a
+// named register without touching the kill ring or system clipboard.
+func (e *Editor) handleYankToRegister() {
+	name, ok := e.ui.ShowKeyPrompt("Yank into register (a-z, A-Z appends):")
+	if !ok {
+		e.ui.SetStatus("Yank cancelled")
+		return
+	}
+
+	var entry killring.Entry
+	if e.buffer.Block != nil {
+		entry = killring.Entry{Text: strings.Join(e.buffer.BlockText(), "\n"), Block: true}
+	} else {
+		var parts []string
+		e.buffer.ForEachCursor(func() {
+			if e.buffer.HasSelection() {
+				parts = append(parts, e.buffer.GetSelection())
+			} else {
+				parts = append(parts, e.buffer.GetCurrentLine())
+			}
+		})
+		entry = killring.Entry{Text: strings.Join(parts, "\n")}
+	}
+
+	if err := e.registers.Set(name, entry); err != nil {
+		e.ui.SetStatus(err.Error())
+		return
+	}
+	e.ui.SetStatus(fmt.Sprintf("Yanked into register %c", name))
+}
+
+func (e *Editor) handlePasteFromRegister() {
+	name, ok := e.ui.ShowKeyPrompt("Paste register (a-z):")
+	if !ok {
+		e.ui.SetStatus("Paste cancelled")
+		return
+	}
+
+	entry, ok := e.registers.Get(name)
+	if !ok || entry.Text == "" {
+		e.ui.SetStatus(fmt.Sprintf("Register %c is empty", name))
+		return
+	}
+
+	e.saveUndo()
+	e.insertEntry(entry)
+	e.ui.SetStatus(fmt.Sprintf("Pasted register %c", name))
+}
+
+// entryPreview summarises an entry on one line for the history picker.
+func entryPreview(entry killring.Entry) string {
+	lines := strings.Split(entry.Text, "\n")
+	preview := strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		preview += fmt.Sprintf("  (+%d lines)", len(lines)-1)
+	}
+	if entry.Block {
+		preview = "[block] " + preview
+	}
+	return preview
+}
@@ -0,0 +1,101 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/clipboard"
+	"github.com/justynroberts/finpup/internal/config"
+	"github.com/justynroberts/finpup/internal/killring"
+)
+
+// newKillRingEditor opens lines with the cursor at the start of the first
+// and an internal clipboard, so tests never touch the system one.
+func newKillRingEditor(t *testing.T, lines ...string) (*Editor, tcell.SimulationScreen) {
+	t.Helper()
+	screen := tcell.NewSimulationScreen("")
+	e := newTestEditorOn(t, screen, "a.txt", "|", config.DefaultConfig)
+	e.buffer.Lines = lines
+	e.clip, _ = clipboard.New("internal", nil)
+	e.killRing = killring.New(killRingSize)
+	e.registers = killring.NewRegisters()
+	return e, screen
+}
+
+func ctrlKey(key tcell.Key) *tcell.EventKey {
+	return tcell.NewEventKey(key, 0, tcell.ModCtrl)
+}
+
+func altKey(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt)
+}
+
+func TestPastePrevious(t *testing.T) {
+	e, _ := newKillRingEditor(t, "one", "two", "")
+	e.handleEvent(ctrlKey(tcell.KeyCtrlC))
+	e.buffer.CursorY = 1
+	e.handleEvent(ctrlKey(tcell.KeyCtrlC))
+	e.buffer.CursorY = 2
+
+	e.handleEvent(ctrlKey(tcell.KeyCtrlV))
+	if got := e.buffer.Lines[2]; got != "two" {
+		t.Fatalf("Expected the latest copy pasted, got %q", got)
+	}
+
+	e.handleEvent(altKey('y'))
+	if got := e.buffer.Lines[2]; got != "one" || cursorLine(e) != "one|" {
+		t.Errorf("Expected Alt+Y to swap in the previous copy, got %q", cursorLine(e))
+	}
+	e.handleEvent(altKey('y'))
+	if got := e.buffer.Lines[2]; got != "two" {
+		t.Errorf("Expected Alt+Y to cycle back to the newest copy, got %q", got)
+	}
+
+	e.handleEvent(ctrlKey(tcell.KeyCtrlZ))
+	if want := []string{"one", "two", ""}; !reflect.DeepEqual(e.buffer.Lines, want) {
+		t.Errorf("Expected one undo to remove the paste, got %q", e.buffer.Lines)
+	}
+}
+
+func TestPastePreviousOnlyAfterPaste(t *testing.T) {
+	e, _ := newKillRingEditor(t, "one", "two", "")
+	e.handleEvent(ctrlKey(tcell.KeyCtrlC))
+	e.buffer.CursorY = 1
+	e.handleEvent(ctrlKey(tcell.KeyCtrlC))
+	e.buffer.CursorY = 2
+
+	// Before any paste
+	e.handleEvent(altKey('y'))
+	if got := e.buffer.Lines[2]; got != "" {
+		t.Errorf("Expected Alt+Y to do nothing before a paste, got %q", got)
+	}
+
+	// After a paste and then another command
+	e.handleEvent(ctrlKey(tcell.KeyCtrlV))
+	typeKeys(e, "!")
+	e.handleEvent(altKey('y'))
+	if got := e.buffer.Lines[2]; got != "two!" {
+		t.Errorf("Expected Alt+Y to do nothing after typing, got %q", got)
+	}
+}
+
+func TestRegisterRoundTrip(t *testing.T) {
+	e, screen := newKillRingEditor(t, "alpha", "beta", "")
+
+	screen.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	e.handleEvent(altKey('r'))
+	e.buffer.CursorY = 1
+	screen.InjectKey(tcell.KeyRune, 'A', tcell.ModNone)
+	e.handleEvent(altKey('r'))
+
+	e.buffer.CursorY = 2
+	screen.InjectKey(tcell.KeyRune, 'a', tcell.ModNone)
+	e.handleEvent(altKey('p'))
+	if want := []string{"alpha", "beta", "alpha", "beta"}; !reflect.DeepEqual(e.buffer.Lines, want) {
+		t.Errorf("Expected register a pasted with the appended line, got %q", e.buffer.Lines)
+	}
+	if _, ok := e.killRing.Latest(); ok {
+		t.Error("Expected yanking into a register to leave the kill ring alone")
+	}
+}
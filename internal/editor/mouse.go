@@ -0,0 +1,123 @@
+package editor
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+const (
+	// doubleClickTime is the longest gap between clicks that still counts
+	// towards a double or triple click.
+	doubleClickTime = 400 * time.Millisecond
+	wheelLines      = 3
+	wheelColumns    = 6
+)
+
+// mouseState tracks button 1 between events to detect drags and
+// multi-clicks.
+type mouseState struct {
+	down      bool
+	clicks    int
+	lastClick time.Time
+	lastX     int
+	lastY     int
+}
+
+func (e *Editor) handleMouse(ev *tcell.EventMouse) {
+	x, y := ev.Position()
+	buttons := ev.Buttons()
+
+	switch {
+	case buttons&tcell.WheelUp != 0:
+		e.ui.Scroll(-wheelLines)
+	case buttons&tcell.WheelDown != 0:
+		e.ui.Scroll(wheelLines)
+	case buttons&tcell.WheelLeft != 0:
+		e.ui.ScrollHorizontal(-wheelColumns)
+	case buttons&tcell.WheelRight != 0:
+		e.ui.ScrollHorizontal(wheelColumns)
+	case buttons&tcell.Button1 != 0:
+		if e.mouse.down {
+			e.handleMouseDrag(x, y)
+		} else {
+			e.handleMousePress(x, y, ev.Modifiers())
+		}
+	case buttons == tcell.ButtonNone:
+		e.mouse.down = false
+	}
+}
+
+func (e *Editor) handleMousePress(x, y int, mods tcell.ModMask) {
+	if e.ui.IsHelpBarRow(y) {
+		if key, ok := e.ui.HelpKeyAt(x); ok {
+			e.handleEvent(tcell.NewEventKey(key, 0, tcell.ModCtrl))
+		}
+		return
+	}
+
+	if sign, ok := e.ui.SignAtScreen(x, y); ok {
+		e.ui.SetStatus(sign.Message)
+		return
+	}
+
+	if line, ok := e.ui.FoldMarkerAt(x, y); ok {
+		e.toggleFold(line)
+		return
+	}
+
+	bx, by, ok := e.ui.ScreenToBuffer(x, y)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(e.mouse.lastClick) < doubleClickTime && bx == e.mouse.lastX && by == e.mouse.lastY {
+		e.mouse.clicks = e.mouse.clicks%3 + 1
+	} else {
+		e.mouse.clicks = 1
+	}
+	e.mouse.lastClick = now
+	e.mouse.lastX, e.mouse.lastY = bx, by
+	e.mouse.down = true
+
+	e.resetCursors()
+	e.ui.FollowCursor()
+
+	if e.mouse.clicks == 1 && mods&tcell.ModShift != 0 {
+		// Shift+click extends the selection from the current position
+		e.buffer.StartSelection()
+		e.buffer.CursorX, e.buffer.CursorY = bx, by
+		e.transientSelection = true
+		return
+	}
+
+	e.clearSelection()
+	e.buffer.CursorX, e.buffer.CursorY = bx, by
+
+	switch e.mouse.clicks {
+	case 2:
+		e.buffer.SelectWord()
+	case 3:
+		e.buffer.SelectLine()
+	}
+	e.transientSelection = e.mouse.clicks > 1
+}
+
+func (e *Editor) handleMouseDrag(x, y int) {
+	bx, by, ok := e.ui.ScreenToBuffer(x, y)
+	if !ok {
+		return
+	}
+
+	if !e.buffer.SelectMode {
+		if bx == e.mouse.lastX && by == e.mouse.lastY {
+			return
+		}
+		// Once the mouse moves, the selection starts where it was pressed
+		e.buffer.SelectMode = true
+		e.buffer.SelectX, e.buffer.SelectY = e.mouse.lastX, e.mouse.lastY
+	}
+	e.buffer.CursorX, e.buffer.CursorY = bx, by
+	e.transientSelection = true
+}
@@ -0,0 +1,121 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/config"
+)
+
+// newMouseEditor opens text in an editor that has been drawn once, so
+// screen cells map to buffer positions. It returns the gutter width.
+func newMouseEditor(t *testing.T, path, text string) (*Editor, int) {
+	t.Helper()
+	e := newTestEditor(t, path, "|"+text, config.DefaultConfig)
+	e.ui.SyntaxTree() // Wait for fold regions, which widen the gutter
+	e.ui.Draw()
+	for x := 0; x < 20; x++ {
+		if bx, _, _ := e.ui.ScreenToBuffer(x, 0); bx > 0 {
+			return e, x - 1
+		}
+	}
+	t.Fatal("Could not find the end of the gutter")
+	return nil, 0
+}
+
+func click(e *Editor, x, y int, mods tcell.ModMask) {
+	e.handleMouse(tcell.NewEventMouse(x, y, tcell.Button1, mods))
+	e.handleMouse(tcell.NewEventMouse(x, y, tcell.ButtonNone, tcell.ModNone))
+}
+
+func TestMouseClicks(t *testing.T) {
+	e, g := newMouseEditor(t, "a.txt", "alpha beta gamma\nsecond line\nthird")
+
+	click(e, g+7, 1, tcell.ModNone)
+	if e.buffer.CursorX != 7 || e.buffer.CursorY != 1 {
+		t.Errorf("Expected a click to move the cursor to (7, 1), got (%d, %d)", e.buffer.CursorX, e.buffer.CursorY)
+	}
+	if e.buffer.SelectMode {
+		t.Error("Expected a plain click to leave selection mode off")
+	}
+
+	// Ctrl+W after a click starts a selection rather than ending one
+	e.handleEvent(tcell.NewEventKey(tcell.KeyCtrlW, 0, tcell.ModCtrl))
+	if !e.buffer.SelectMode {
+		t.Error("Expected Ctrl+W after a click to turn selection mode on")
+	}
+
+	click(e, g+7, 0, tcell.ModNone)
+	click(e, g+7, 0, tcell.ModNone)
+	if startX, _, endX, _ := e.buffer.SelectionBounds(); !e.buffer.HasSelection() || startX != 6 || endX != 10 {
+		t.Errorf("Expected a double click to select beta, got %d-%d", startX, endX)
+	}
+
+	click(e, g+7, 0, tcell.ModNone)
+	if startX, startY, endX, endY := e.buffer.SelectionBounds(); startX != 0 || startY != 0 || endX != 0 || endY != 1 {
+		t.Errorf("Expected a triple click to select the line, got (%d, %d)-(%d, %d)", startX, startY, endX, endY)
+	}
+
+	// Shift+click extends from the cursor
+	click(e, g+2, 2, tcell.ModNone)
+	click(e, g+4, 0, tcell.ModShift)
+	if startX, startY, endX, endY := e.buffer.SelectionBounds(); startX != 4 || startY != 0 || endX != 2 || endY != 2 {
+		t.Errorf("Expected Shift+click to select (4, 0)-(2, 2), got (%d, %d)-(%d, %d)", startX, startY, endX, endY)
+	}
+}
+
+func TestMouseDrag(t *testing.T) {
+	e, g := newMouseEditor(t, "a.txt", "alpha beta gamma\nsecond line")
+
+	e.handleMouse(tcell.NewEventMouse(g+2, 0, tcell.Button1, tcell.ModNone))
+	e.handleMouse(tcell.NewEventMouse(g+2, 0, tcell.Button1, tcell.ModNone))
+	if e.buffer.SelectMode {
+		t.Error("Expected no selection until the mouse moves")
+	}
+	e.handleMouse(tcell.NewEventMouse(g+3, 1, tcell.Button1, tcell.ModNone))
+	e.handleMouse(tcell.NewEventMouse(g+3, 1, tcell.ButtonNone, tcell.ModNone))
+
+	if startX, startY, endX, endY := e.buffer.SelectionBounds(); !e.buffer.HasSelection() || startX != 2 || startY != 0 || endX != 3 || endY != 1 {
+		t.Errorf("Expected the drag to select (2, 0)-(3, 1), got (%d, %d)-(%d, %d)", startX, startY, endX, endY)
+	}
+}
+
+func TestMouseHelpBarAndFoldMarker(t *testing.T) {
+	e, g := newMouseEditor(t, "a.go", "package a\n\nfunc f() {\n\treturn\n}\n")
+
+	// The fold marker sits in the last gutter columns, before the text
+	marker := -1
+	for x := 0; x < g; x++ {
+		if _, ok := e.ui.FoldMarkerAt(x, 2); ok {
+			marker = x
+		}
+	}
+	if marker < 0 {
+		t.Fatal("Expected a fold marker on the function")
+	}
+	click(e, marker, 2, tcell.ModNone)
+	if !e.ui.Folded(2) {
+		t.Error("Expected a click on the marker to fold the function")
+	}
+	click(e, marker, 2, tcell.ModNone)
+	if e.ui.Folded(2) {
+		t.Error("Expected a second click to unfold it")
+	}
+
+	helpY := -1
+	for y := 0; y < 100; y++ {
+		if e.ui.IsHelpBarRow(y) {
+			helpY = y
+			break
+		}
+	}
+	for x := 0; x < 200; x++ {
+		if key, ok := e.ui.HelpKeyAt(x); ok && key == tcell.KeyCtrlB {
+			click(e, x, helpY, tcell.ModNone)
+			break
+		}
+	}
+	if e.buffer.CursorY != len(e.buffer.Lines)-1 {
+		t.Errorf("Expected the ^B Bottom entry to jump to the last line, got line %d", e.buffer.CursorY)
+	}
+}
@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/config"
+)
+
+// paste sends keys as a bracketed paste. '\r' stands for Enter, '\n' for
+// Ctrl+J and '\t' for Tab, as terminals deliver them.
+func paste(e *Editor, keys string) {
+	e.handleEvent(tcell.NewEventPaste(true))
+	for _, r := range keys {
+		switch r {
+		case '\r':
+			e.handleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+		case '\n':
+			e.handleEvent(tcell.NewEventKey(tcell.KeyCtrlJ, 0, tcell.ModNone))
+		case '\t':
+			e.handleEvent(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+		default:
+			e.handleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+	}
+	e.handleEvent(tcell.NewEventPaste(false))
+}
+
+func TestBracketedPaste(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string
+		want []string
+	}{
+		{"enter", "a\rb", []string{"xa", "b"}},
+		{"ctrl+j", "a\nb", []string{"xa", "b"}},
+		{"crlf", "a\r\nb\r\n", []string{"xa", "b", ""}},
+		{"tab", "\ta", []string{"x\ta"}},
+		{"no auto-indent or auto-close", "if y {\r\tz(\r", []string{"xif y {", "\tz(", ""}},
+		{"no electric outdent", "{\r\t\t}", []string{"x{", "\t\t}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEditor(t, "a.go", "x|", config.DefaultConfig)
+			paste(e, tt.keys)
+
+			if !reflect.DeepEqual(e.buffer.Lines, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, e.buffer.Lines)
+			}
+			if e.pasting {
+				t.Error("Expected the paste to have ended")
+			}
+		})
+	}
+}
+
+func TestBracketedPasteUndo(t *testing.T) {
+	e := newTestEditor(t, "a.go", "x|", config.DefaultConfig)
+	paste(e, "one\rtwo\rthree")
+
+	if len(e.undoStack) != 1 {
+		t.Fatalf("Expected one undo step, got %d", len(e.undoStack))
+	}
+	e.handleUndo()
+	if want := []string{"x"}; !reflect.DeepEqual(e.buffer.Lines, want) {
+		t.Errorf("Expected %q after undo, got %q", want, e.buffer.Lines)
+	}
+}
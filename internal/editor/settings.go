@@ -0,0 +1,125 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+	"github.com/justynroberts/finpup/internal/editorconfig"
+)
+
+// applySettings sets up the buffer's indentation from the config and the
+// style detected in the file, then applies the .editorconfig files for its
+// path over it. It returns the .editorconfig files that had a matching
+// section.
+func applySettings(buf *buffer.Buffer, cfg *config.Config) ([]string, error) {
+	var props editorconfig.Properties
+	var sources []string
+	var err error
+	if buf.FilePath != "" {
+		props, sources, err = editorconfig.Lookup(buf.FilePath)
+	}
+
+	// Latin-1 cannot be recognised, so a file read as UTF-8 is read again,
+	// unless it has been edited since. Reading resets the line endings and
+	// detected indentation, so it comes before anything else is applied.
+	if props["charset"] == buffer.CharsetLatin1 && buf.Format.Charset == buffer.CharsetUTF8 && !buf.Modified {
+		buf.Format.Charset = buffer.CharsetLatin1
+		if err := buf.Load(); err != nil {
+			return sources, err
+		}
+	}
+
+	buf.TabSize = cfg.Editor.TabSize
+	buf.IndentSize = 0
+	buf.UseTabs = cfg.Editor.UseTabs
+	if buf.DetectedIndent != nil {
+		buf.SetIndentStyle(*buf.DetectedIndent)
+	}
+	buf.MaxLineLength = 0
+	if err != nil {
+		return nil, err
+	}
+
+	switch props["indent_style"] {
+	case "tab":
+		buf.UseTabs = true
+	case "space":
+		buf.UseTabs = false
+	}
+	if n, ok := props.Int("tab_width"); ok {
+		buf.TabSize = n
+	}
+	if n, ok := props.Int("indent_size"); ok {
+		buf.IndentSize = n
+	}
+	if n, ok := props.Int("max_line_length"); ok {
+		buf.MaxLineLength = n
+	}
+
+	format := &buf.Format
+	switch props["end_of_line"] {
+	case "lf":
+		format.EOL = "\n"
+	case "crlf":
+		format.EOL = "\r\n"
+	}
+	if v, ok := props.Bool("trim_trailing_whitespace"); ok {
+		format.TrimTrailingWhitespace = v
+	}
+	if v, ok := props.Bool("insert_final_newline"); ok {
+		format.FinalNewline = v
+	}
+	switch charset := props["charset"]; charset {
+	case buffer.CharsetUTF8, buffer.CharsetUTF8BOM, buffer.CharsetUTF16BE, buffer.CharsetUTF16LE:
+		format.Charset = charset
+	}
+	return sources, nil
+}
+
+// handleFileInfo shows the settings in effect for the buffer and where
+// they came from.
+func (e *Editor) handleFileInfo() {
+	b := e.buffer
+	yesNo := func(v bool) string {
+		if v {
+			return "yes"
+		}
+		return "no"
+	}
+
+	path := b.FilePath
+	if path == "" {
+		path = "(unnamed)"
+	}
+	language := e.ui.Language()
+	if language == "" {
+		language = "plain text"
+	}
+	indent := b.IndentStyle().String()
+	if d := b.DetectedIndent; d != nil && *d == b.IndentStyle() {
+		indent += " (detected)"
+	}
+	maxLength := "none"
+	if b.MaxLineLength > 0 {
+		maxLength = fmt.Sprint(b.MaxLineLength)
+	}
+	sources := "none"
+	if len(e.editorConfig) > 0 {
+		sources = strings.Join(e.editorConfig, ", ")
+	}
+
+	e.ui.ShowInfo("File settings", []string{
+		"File:            " + path,
+		"Language:        " + language,
+		"Indentation:     " + indent,
+		"Tab width:       " + fmt.Sprint(b.TabStop()),
+		"Line endings:    " + strings.ToUpper(b.Format.EOLName()),
+		"Charset:         " + b.Format.Charset,
+		"Final newline:   " + yesNo(b.Format.FinalNewline),
+		"Trim whitespace: " + yesNo(b.Format.TrimTrailingWhitespace),
+		"Max line length: " + maxLength,
+		"EditorConfig:    " + sources,
+	})
+}
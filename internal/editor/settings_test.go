@@ -0,0 +1,36 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+)
+
+func TestApplySettingsLatin1KeepsOtherProperties(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".editorconfig"), []byte("root = true\n[*]\ncharset = latin1\nend_of_line = crlf\nindent_style = tab\n"), 0644)
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("caf\xe9:\n  au lait\n"), 0644)
+
+	buf, err := buffer.New(path)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	cfg := config.DefaultConfig
+	if _, err := applySettings(buf, &cfg); err != nil {
+		t.Fatalf("applySettings failed: %v", err)
+	}
+
+	if buf.Format.Charset != buffer.CharsetLatin1 || buf.Lines[0] != "café:" {
+		t.Errorf("Expected the file read again as latin1, got %q as %s", buf.Lines[0], buf.Format.Charset)
+	}
+	if buf.Format.EOL != "\r\n" {
+		t.Errorf("Expected end_of_line = crlf to survive the re-read, got %q", buf.Format.EOL)
+	}
+	if !buf.UseTabs {
+		t.Error("Expected indent_style = tab to win over the detected 2 spaces")
+	}
+}
@@ -0,0 +1,128 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/syntax"
+)
+
+// syntaxTree returns the structural parse of the buffer, reporting in the
+// status bar why there is none.
+func (e *Editor) syntaxTree() *syntax.Tree {
+	tree := e.ui.SyntaxTree()
+	if tree == nil {
+		if err := e.ui.SyntaxError(); err != nil {
+			e.ui.SetStatus(fmt.Sprintf("Syntax error: %v", err))
+		} else {
+			e.ui.SetStatus("No syntax tree for this file type")
+		}
+	}
+	return tree
+}
+
+// cursorRange returns the primary selection, or the cursor position, as
+// document offsets.
+func (e *Editor) cursorRange(tree *syntax.Tree) (int, int) {
+	if e.buffer.HasSelection() {
+		startX, startY, endX, endY := e.buffer.SelectionBounds()
+		return tree.Offset(startX, startY), tree.Offset(endX, endY)
+	}
+	offset := tree.Offset(e.buffer.CursorX, e.buffer.CursorY)
+	return offset, offset
+}
+
+// selectNode selects n with the cursor at its end.
+func (e *Editor) selectNode(tree *syntax.Tree, n *syntax.Node) {
+	startX, startY := tree.Position(n.Start)
+	endX, endY := tree.Position(n.End)
+	e.buffer.Cursor = buffer.Cursor{
+		CursorX:    endX,
+		CursorY:    endY,
+		SelectMode: true,
+		SelectX:    startX,
+		SelectY:    startY,
+	}
+	e.transientSelection = true
+}
+
+// handleExpandSelection grows the selection to the enclosing syntax node,
+// remembering the previous selection so it can be shrunk back. Like the
+// other syntax commands it acts on the primary cursor only, as the history
+// only remembers one selection, and drops any extra cursors.
+func (e *Editor) handleExpandSelection() {
+	e.buffer.ClearExtraCursors()
+	tree := e.syntaxTree()
+	if tree == nil {
+		return
+	}
+
+	start, end := e.cursorRange(tree)
+	n := tree.Expand(start, end)
+	if n == nil {
+		return
+	}
+
+	if e.buffer.Cursor != e.expanded {
+		e.expandHistory = nil
+	}
+	e.expandHistory = append(e.expandHistory, e.buffer.Cursor)
+	e.selectNode(tree, n)
+	e.expanded = e.buffer.Cursor
+	e.ui.SetStatus(n.Kind)
+}
+
+// handleShrinkSelection undoes the last expansion.
+func (e *Editor) handleShrinkSelection() {
+	e.buffer.ClearExtraCursors()
+	if len(e.expandHistory) == 0 || e.buffer.Cursor != e.expanded {
+		e.expandHistory = nil
+		return
+	}
+
+	last := len(e.expandHistory) - 1
+	e.buffer.Cursor = e.expandHistory[last]
+	e.expandHistory = e.expandHistory[:last]
+	e.expanded = e.buffer.Cursor
+}
+
+// handleSyntaxParent moves the cursor to the start of the node enclosing
+// the one at the cursor.
+func (e *Editor) handleSyntaxParent() {
+	e.buffer.ClearExtraCursors()
+	tree := e.syntaxTree()
+	if tree == nil {
+		return
+	}
+
+	offset := tree.Offset(e.buffer.CursorX, e.buffer.CursorY)
+	n := tree.Smallest(offset, offset)
+	for n.Parent != nil && n.Start == offset {
+		n = n.Parent
+	}
+	e.jumpToNode(tree, n)
+}
+
+// handleNextSibling moves the cursor to the start of the next node at the
+// same level as the one at the cursor.
+func (e *Editor) handleNextSibling() {
+	e.buffer.ClearExtraCursors()
+	tree := e.syntaxTree()
+	if tree == nil {
+		return
+	}
+
+	offset := tree.Offset(e.buffer.CursorX, e.buffer.CursorY)
+	n := syntax.NextSibling(syntax.Outermost(tree.Smallest(offset, offset)))
+	if n == nil {
+		e.ui.SetStatus("No next sibling")
+		return
+	}
+	e.jumpToNode(tree, n)
+}
+
+func (e *Editor) jumpToNode(tree *syntax.Tree, n *syntax.Node) {
+	e.clearSelection()
+	e.buffer.CursorX, e.buffer.CursorY = tree.Position(n.Start)
+	e.ui.SetStatus(n.Kind)
+}
@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+)
+
+func TestSyntaxCommandsUsePrimaryCursor(t *testing.T) {
+	commands := []struct {
+		name string
+		run  func(e *Editor)
+	}{
+		{"expand", (*Editor).handleExpandSelection},
+		{"shrink", (*Editor).handleShrinkSelection},
+		{"parent", (*Editor).handleSyntaxParent},
+		{"next sibling", (*Editor).handleNextSibling},
+	}
+
+	for _, c := range commands {
+		t.Run(c.name, func(t *testing.T) {
+			e := newTestEditor(t, "a.json", `{"a": [1, |2], "b": 3}`, config.DefaultConfig)
+			e.buffer.SetCursors([]buffer.Cursor{e.buffer.Cursor, {CursorX: 16}})
+
+			c.run(e)
+			if e.buffer.HasMultipleCursors() {
+				t.Errorf("Expected %s to leave only the primary cursor, got %d", c.name, e.buffer.CursorCount())
+			}
+		})
+	}
+
+	// Expanding still works from the primary cursor
+	e := newTestEditor(t, "a.json", `{"a": [1, |2], "b": 3}`, config.DefaultConfig)
+	e.buffer.SetCursors([]buffer.Cursor{e.buffer.Cursor, {CursorX: 16}})
+	e.handleExpandSelection()
+	if startX, _, endX, _ := e.buffer.SelectionBounds(); !e.buffer.HasSelection() || startX > 10 || endX < 11 {
+		t.Errorf("Expected a selection around 2, got %d-%d", startX, endX)
+	}
+}
@@ -0,0 +1,40 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/config"
+	"github.com/justynroberts/finpup/pkg/themes"
+)
+
+func TestCycleTheme(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig
+	cfg.Theme.Current = themes.AllThemes[0].Name
+	e := newTestEditor(t, "a.go", "ab|", cfg)
+	altT := tcell.NewEventKey(tcell.KeyRune, 't', tcell.ModAlt)
+
+	for i := 1; i <= len(themes.AllThemes); i++ {
+		e.handleEvent(altT)
+		want := themes.AllThemes[i%len(themes.AllThemes)].Name
+		if e.config.Theme.Current != want {
+			t.Fatalf("Expected theme %q after %d presses, got %q", want, i, e.config.Theme.Current)
+		}
+	}
+
+	e.handleEvent(altT)
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := themes.AllThemes[1].Name; saved.Theme.Current != want {
+		t.Errorf("Expected %q saved, got %q", want, saved.Theme.Current)
+	}
+
+	// Ctrl+H is the same byte as Backspace, so it must not cycle themes
+	e.handleEvent(tcell.NewEventKey(tcell.KeyCtrlH, 0, tcell.ModCtrl))
+	if e.config.Theme.Current != themes.AllThemes[1].Name || cursorLine(e) != "a|" {
+		t.Errorf("Expected Ctrl+H to delete a character, got theme %q and %q", e.config.Theme.Current, cursorLine(e))
+	}
+}
@@ -0,0 +1,176 @@
+// Package editorconfig finds the .editorconfig settings that apply to a
+// file, as described at https://editorconfig.org.
+package editorconfig
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the files Lookup reads.
+const FileName = ".editorconfig"
+
+// Properties are the settings for one file, keyed by lower-case name.
+// Values of the properties the specification defines are lower-cased too.
+type Properties map[string]string
+
+// Int returns a numeric property.
+func (p Properties) Int(key string) (int, bool) {
+	n, err := strconv.Atoi(p[key])
+	return n, err == nil && n > 0
+}
+
+// Bool returns a true/false property.
+func (p Properties) Bool(key string) (value, ok bool) {
+	switch p[key] {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+// Section is one [glob] block of a file.
+type Section struct {
+	Glob       string
+	Properties Properties
+}
+
+// File is a parsed .editorconfig file.
+type File struct {
+	// Root stops the search for files in parent directories.
+	Root     bool
+	Sections []Section
+}
+
+// knownProperties have case-insensitive values.
+var knownProperties = map[string]bool{
+	"indent_style": true, "indent_size": true, "tab_width": true,
+	"end_of_line": true, "charset": true, "trim_trailing_whitespace": true,
+	"insert_final_newline": true, "max_line_length": true, "root": true,
+}
+
+// Parse reads an .editorconfig file. Lines it cannot make sense of are
+// skipped, as other editors do, so one typo does not lose the whole file;
+// properties under a malformed section header are skipped with it.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	var section *Section
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				section = &Section{Properties: Properties{}}
+				continue
+			}
+			f.Sections = append(f.Sections, Section{Glob: line[1 : len(line)-1], Properties: Properties{}})
+			section = &f.Sections[len(f.Sections)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if knownProperties[key] {
+			value = strings.ToLower(value)
+		}
+		if section == nil {
+			// Only root may appear before the first section
+			if key == "root" {
+				f.Root = value == "true"
+			}
+			continue
+		}
+		section.Properties[key] = value
+	}
+	return f, scanner.Err()
+}
+
+// Lookup returns the properties that apply to path and the files they were
+// read from, outermost first. Files in deeper directories, and later
+// sections within a file, take precedence.
+func Lookup(path string) (Properties, []string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Collect files from the file's directory up to the first root
+	type found struct {
+		dir  string
+		file *File
+	}
+	var files []found
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		name := filepath.Join(dir, FileName)
+		file, err := os.Open(name)
+		if err == nil {
+			f, err := Parse(file)
+			file.Close()
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", name, err)
+			}
+			files = append(files, found{dir, f})
+			if f.Root {
+				break
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+
+	props := Properties{}
+	var sources []string
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		used := false
+		for _, s := range f.file.Sections {
+			if !Match(s.Glob, filepath.ToSlash(f.dir), filepath.ToSlash(abs)) {
+				continue
+			}
+			for k, v := range s.Properties {
+				props[k] = v
+			}
+			used = true
+		}
+		if used {
+			sources = append(sources, filepath.Join(f.dir, FileName))
+		}
+	}
+
+	for k, v := range props {
+		if v == "unset" {
+			delete(props, k)
+		}
+	}
+	// tab_width defaults to indent_size, and indent_size to tab_width when
+	// indenting with tabs
+	if _, ok := props["tab_width"]; !ok {
+		if size := props["indent_size"]; size != "" && size != "tab" {
+			props["tab_width"] = size
+		}
+	}
+	if props["indent_size"] == "tab" || (props["indent_style"] == "tab" && props["indent_size"] == "") {
+		if width, ok := props["tab_width"]; ok {
+			props["indent_size"] = width
+		}
+	}
+	return props, sources, nil
+}
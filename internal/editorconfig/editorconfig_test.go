@@ -0,0 +1,103 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		glob, path string
+		want       bool
+	}{
+		{"*", "/p/a.go", true},
+		{"*.go", "/p/sub/dir/a.go", true},
+		{"*.go", "/p/a.got", false},
+		{"*.{js,ts}", "/p/x/a.ts", true},
+		{"*.{js,ts}", "/p/a.py", false},
+		{"Makefile", "/p/src/Makefile", true},
+		{"/Makefile", "/p/src/Makefile", false},
+		{"src/*.c", "/p/src/a.c", true},
+		{"src/*.c", "/p/src/sub/a.c", false},
+		{"src/**/*.c", "/p/src/a.c", true},
+		{"src/**/*.c", "/p/src/sub/deep/a.c", true},
+		{"file[0-9].txt", "/p/file5.txt", true},
+		{"file[!0-9].txt", "/p/file5.txt", false},
+		{"a?.md", "/p/ab.md", true},
+		{"log{1..10}.txt", "/p/log7.txt", true},
+		{"log{1..10}.txt", "/p/log11.txt", false},
+		{"{single}.txt", "/p/{single}.txt", true},
+		{"a,b", "/p/a,b", true},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.glob, "/p", tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader("# top\nroot = true\n\n[*]\nIndent_Style = Tab\nx_custom = KeepCase\n; comment\n[*.md]\ntrim_trailing_whitespace=false\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !f.Root || len(f.Sections) != 2 {
+		t.Fatalf("Unexpected file %+v", f)
+	}
+	if got := f.Sections[0].Properties; got["indent_style"] != "tab" || got["x_custom"] != "KeepCase" {
+		t.Errorf("Unexpected properties %v", got)
+	}
+
+}
+
+func TestParseSkipsJunk(t *testing.T) {
+	f, err := Parse(strings.NewReader("[*]\nindent_size = 2\nnonsense\ntab_width = 8\n[*.go\nindent_style = tab\n[*.md]\ncharset = utf-8\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(f.Sections) != 2 {
+		t.Fatalf("Expected the malformed section left out, got %+v", f.Sections)
+	}
+	want := Properties{"indent_size": "2", "tab_width": "8"}
+	if got := f.Sections[0].Properties; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v around the junk line, got %v", want, got)
+	}
+	if got := f.Sections[1].Properties; got["charset"] != "utf-8" || len(got) != 1 {
+		t.Errorf("Expected only the [*.md] properties, got %v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "project", "src")
+	os.MkdirAll(sub, 0755)
+	os.WriteFile(filepath.Join(dir, FileName), []byte("[*]\nindent_size = 8\ncharset = latin1\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "project", FileName), []byte("root = true\n[*]\nindent_style = space\nindent_size = 2\nend_of_line = crlf\n[*.go]\nindent_style = tab\nindent_size = unset\n"), 0644)
+	os.WriteFile(filepath.Join(sub, FileName), []byte("[Makefile]\nindent_style = tab\n[*.go]\ntab_width = 4\n"), 0644)
+
+	props, sources, err := Lookup(filepath.Join(sub, "main.go"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if props["indent_style"] != "tab" || props["tab_width"] != "4" || props["indent_size"] != "4" {
+		t.Errorf("Expected tabs 4 wide, got %v", props)
+	}
+	if props["end_of_line"] != "crlf" {
+		t.Errorf("Expected properties from the project root, got %v", props)
+	}
+	if _, ok := props["charset"]; ok {
+		t.Error("Expected the search to stop at root = true")
+	}
+	if len(sources) != 2 || sources[1] != filepath.Join(sub, FileName) {
+		t.Errorf("Unexpected sources %v", sources)
+	}
+
+	props, _, _ = Lookup(filepath.Join(sub, "notes.txt"))
+	if props["indent_size"] != "2" || props["tab_width"] != "2" {
+		t.Errorf("Expected tab_width to default to indent_size, got %v", props)
+	}
+}
@@ -0,0 +1,133 @@
+package editorconfig
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// numRange is a {num1..num2} pattern, matched by a capture group.
+type numRange struct{ lo, hi int }
+
+// Match reports whether the section glob, from an .editorconfig in dir,
+// matches path. Both use forward slashes. A glob without a slash matches
+// the file name in any directory below dir.
+func Match(glob, dir, path string) bool {
+	switch {
+	case strings.HasPrefix(glob, "/"):
+		glob = glob[1:]
+	case !strings.Contains(glob, "/"):
+		glob = "**/" + glob
+	}
+	// A leading **/ also matches files directly in dir
+	prefix := regexp.QuoteMeta(strings.TrimSuffix(dir, "/")) + "/"
+	if rest, ok := strings.CutPrefix(glob, "**/"); ok {
+		prefix += "(?:.*/)?"
+		glob = rest
+	}
+
+	var ranges []numRange
+	pattern, _ := translate(glob, &ranges, false)
+	re, err := regexp.Compile("^" + prefix + pattern + "$")
+	if err != nil {
+		return false
+	}
+	m := re.FindStringSubmatch(path)
+	if m == nil {
+		return false
+	}
+	for i, r := range ranges {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil || n < r.lo || n > r.hi {
+			return false
+		}
+	}
+	return true
+}
+
+var rangePattern = regexp.MustCompile(`^\{([+-]?\d+)\.\.([+-]?\d+)\}`)
+
+// translate converts glob to a regular expression, up to an unmatched "}"
+// or "," when nested inside braces. It returns the expression and the rest
+// of glob.
+func translate(glob string, ranges *[]numRange, nested bool) (string, string) {
+	var sb strings.Builder
+	for glob != "" {
+		c := glob[0]
+		switch {
+		case c == '\\' && len(glob) > 1:
+			sb.WriteString(regexp.QuoteMeta(glob[1:2]))
+			glob = glob[2:]
+		case strings.HasPrefix(glob, "/**/"):
+			// Also matches no directories at all
+			sb.WriteString("(?:/.*)?/")
+			glob = glob[4:]
+		case strings.HasPrefix(glob, "**"):
+			sb.WriteString(".*")
+			glob = glob[2:]
+		case c == '*':
+			sb.WriteString("[^/]*")
+			glob = glob[1:]
+		case c == '?':
+			sb.WriteString("[^/]")
+			glob = glob[1:]
+		case c == '[':
+			end := strings.IndexByte(glob, ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				glob = glob[1:]
+				continue
+			}
+			class := glob[1:end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			glob = glob[end+1:]
+		case c == '{':
+			if m := rangePattern.FindStringSubmatch(glob); m != nil {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[2])
+				*ranges = append(*ranges, numRange{min(lo, hi), max(lo, hi)})
+				sb.WriteString(`([+-]?\d+)`)
+				glob = glob[len(m[0]):]
+				continue
+			}
+			alts, rest, ok := translateBraces(glob[1:], ranges)
+			if !ok {
+				sb.WriteString(`\{`)
+				glob = glob[1:]
+				continue
+			}
+			sb.WriteString("(?:" + strings.Join(alts, "|") + ")")
+			glob = rest
+		case nested && (c == '}' || c == ','):
+			return sb.String(), glob
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[:1]))
+			glob = glob[1:]
+		}
+	}
+	return sb.String(), ""
+}
+
+// translateBraces translates the alternatives of a {a,b} group, given the
+// glob after its "{". A group without a comma or closing brace is not a
+// group.
+func translateBraces(glob string, ranges *[]numRange) ([]string, string, bool) {
+	saved := len(*ranges)
+	var alts []string
+	for {
+		alt, rest := translate(glob, ranges, true)
+		alts = append(alts, alt)
+		switch {
+		case strings.HasPrefix(rest, ","):
+			glob = rest[1:]
+		case strings.HasPrefix(rest, "}") && len(alts) > 1:
+			return alts, rest[1:], true
+		default:
+			*ranges = (*ranges)[:saved]
+			return nil, "", false
+		}
+	}
+}
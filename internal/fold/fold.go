@@ -0,0 +1,187 @@
+// Package fold finds foldable regions of a document and tracks which of
+// them are closed.
+package fold
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/justynroberts/finpup/internal/syntax"
+)
+
+// Region is a foldable range of lines. Start stays visible as the fold's
+// summary line; Start+1 through End are hidden while it is closed. Level
+// is 1 for a region no other region contains, 2 for one inside that, and
+// so on.
+type Region struct {
+	Start, End int
+	Level      int
+}
+
+// Regions is a set of regions sorted by start line, at most one per line.
+type Regions []Region
+
+// Starting returns the region that starts on line y.
+func (rs Regions) Starting(y int) (Region, bool) {
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].Start >= y })
+	if i < len(rs) && rs[i].Start == y {
+		return rs[i], true
+	}
+	return Region{}, false
+}
+
+// Innermost returns the smallest region that starts on or contains line y.
+func (rs Regions) Innermost(y int) (Region, bool) {
+	var best Region
+	found := false
+	for _, r := range rs {
+		if r.Start > y {
+			break
+		}
+		if y <= r.End && (!found || r.End-r.Start < best.End-best.Start) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
+
+// Indentation finds a region wherever a line is followed by more deeply
+// indented ones. Blank lines do not end a region but are not included at
+// its end. A tab counts as tabSize columns.
+func Indentation(lines []string, tabSize int) Regions {
+	var regions []Region
+	// open holds the lines whose regions are still growing, innermost last
+	type pending struct{ line, indent int }
+	var open []pending
+	lastCode := -1
+
+	closeTo := func(indent int) {
+		for len(open) > 0 && open[len(open)-1].indent >= indent {
+			p := open[len(open)-1]
+			open = open[:len(open)-1]
+			if lastCode > p.line {
+				regions = append(regions, Region{Start: p.line, End: lastCode})
+			}
+		}
+	}
+
+	for y, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := indentWidth(line, tabSize)
+		closeTo(indent)
+		open = append(open, pending{y, indent})
+		lastCode = y
+	}
+	closeTo(0)
+	return normalize(regions)
+}
+
+func indentWidth(line string, tabSize int) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += tabSize - width%tabSize
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// Brackets finds a region from each opening bracket to its partner on a
+// later line. brackets returns the offsets of the bracket characters on a
+// line that are code, not inside strings or comments. A closing bracket
+// that starts its line stays visible below the fold.
+func Brackets(lines []string, brackets func(y int) []int) Regions {
+	var regions []Region
+	var open []int
+	for y, line := range lines {
+		for _, x := range brackets(y) {
+			switch line[x] {
+			case '(', '[', '{':
+				open = append(open, y)
+			case ')', ']', '}':
+				if len(open) == 0 {
+					continue
+				}
+				start := open[len(open)-1]
+				open = open[:len(open)-1]
+				if end := closingEnd(lines, y); end > start {
+					regions = append(regions, Region{Start: start, End: end})
+				}
+			}
+		}
+	}
+	return normalize(regions)
+}
+
+// Tree finds a region for every syntax node spanning several lines.
+func Tree(tree *syntax.Tree, lines []string) Regions {
+	var regions []Region
+	var walk func(n *syntax.Node)
+	walk = func(n *syntax.Node) {
+		if n != tree.Root {
+			_, start := tree.Position(n.Start)
+			_, end := tree.Position(max(n.End-1, n.Start))
+			if end = closingEnd(lines, end); end > start {
+				regions = append(regions, Region{Start: start, End: end})
+			}
+		}
+		for _, c := range n.Children {
+			walk(c)
+		}
+	}
+	walk(tree.Root)
+	return normalize(regions)
+}
+
+// closingEnd is the last line to hide for a region ending on line y: the
+// line before when y starts with the closing bracket, so "}" stays shown.
+func closingEnd(lines []string, y int) int {
+	if y < len(lines) {
+		if t := strings.TrimSpace(lines[y]); t != "" && strings.ContainsRune(")]}", rune(t[0])) {
+			return y - 1
+		}
+	}
+	return y
+}
+
+// normalize sorts regions, keeps the longest one per start line and sets
+// their nesting levels.
+func normalize(regions []Region) Regions {
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].Start != regions[j].Start {
+			return regions[i].Start < regions[j].Start
+		}
+		return regions[i].End > regions[j].End
+	})
+
+	var out Regions
+	var stack []int // ends of the regions enclosing the current one
+	for _, r := range regions {
+		if len(out) > 0 && out[len(out)-1].Start == r.Start {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1] < r.Start {
+			stack = stack[:len(stack)-1]
+		}
+		r.Level = len(stack) + 1
+		stack = append(stack, r.End)
+		out = append(out, r)
+	}
+	return out
+}
+
+// Merge combines region sets, keeping the longest region per start line.
+func Merge(sets ...Regions) Regions {
+	var all []Region
+	for _, s := range sets {
+		all = append(all, s...)
+	}
+	return normalize(all)
+}
@@ -0,0 +1,125 @@
+package fold
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/justynroberts/finpup/internal/syntax"
+)
+
+func TestIndentation(t *testing.T) {
+	lines := strings.Split(`def a():
+    if x:
+        y()
+
+    z()
+b = 1
+	tab
+`, "\n")
+
+	got := Indentation(lines, 4)
+	want := Regions{{Start: 0, End: 4, Level: 1}, {Start: 1, End: 2, Level: 2}, {Start: 5, End: 6, Level: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Indentation() = %v, want %v", got, want)
+	}
+}
+
+func TestBracketsKeepClosingLineVisible(t *testing.T) {
+	lines := strings.Split(`x = [
+  1, (2,
+    3)]
+y = {
+}
+z = { a }`, "\n")
+	all := func(y int) []int {
+		var offsets []int
+		for i, c := range lines[y] {
+			if strings.ContainsRune("()[]{}", c) {
+				offsets = append(offsets, i)
+			}
+		}
+		return offsets
+	}
+
+	got := Brackets(lines, all)
+	// "y = {\n}" would hide nothing, so it is not a region
+	want := Regions{{Start: 0, End: 2, Level: 1}, {Start: 1, End: 2, Level: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Brackets() = %v, want %v", got, want)
+	}
+}
+
+func TestTree(t *testing.T) {
+	text := `{
+  "a": {
+    "b": 1
+  },
+  "c": [1, 2]
+}`
+	tree, err := syntax.Parse("json", text)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	got := Tree(tree, strings.Split(text, "\n"))
+	want := Regions{{Start: 0, End: 4, Level: 1}, {Start: 1, End: 2, Level: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tree() = %v, want %v", got, want)
+	}
+}
+
+func TestFoldsNavigation(t *testing.T) {
+	var f Folds
+	f.Close(Region{Start: 2, End: 5})
+	f.Close(Region{Start: 3, End: 4})
+	f.Close(Region{Start: 8, End: 9})
+
+	if n := f.Next(2); n != 6 {
+		t.Errorf("Next(2) = %d, want 6", n)
+	}
+	if n := f.Next(8); n != 10 {
+		t.Errorf("Next(8) = %d, want 10", n)
+	}
+	if p := f.Prev(6); p != 2 {
+		t.Errorf("Prev(6) = %d, want 2", p)
+	}
+	if p := f.Prev(5); p != 2 {
+		t.Errorf("Prev(5) = %d, want 2 for a hidden line", p)
+	}
+	if _, ok := f.Hidden(4); !ok {
+		t.Error("Expected line 4 to be hidden")
+	}
+	if _, ok := f.Hidden(2); ok {
+		t.Error("Expected a fold's first line to stay visible")
+	}
+
+	f.Reveal(4)
+	if _, ok := f.Hidden(4); ok {
+		t.Error("Expected Reveal to open the folds hiding line 4")
+	}
+	if _, ok := f.Closed(8); !ok {
+		t.Error("Expected Reveal to leave other folds closed")
+	}
+}
+
+func TestFoldsFollowEdits(t *testing.T) {
+	var f Folds
+	f.Close(Region{Start: 1, End: 3})
+	f.Close(Region{Start: 6, End: 8})
+
+	// Two lines inserted at line 4
+	f.Shift(4, 2)
+	if r, ok := f.Closed(8); !ok || r.End != 10 {
+		t.Errorf("Closed(8) = %v, %v; want the shifted fold", r, ok)
+	}
+
+	// The first region grew; the second no longer exists
+	f.Sync(Regions{{Start: 1, End: 4, Level: 1}})
+	if r, ok := f.Closed(1); !ok || r.End != 4 {
+		t.Errorf("Closed(1) = %v, %v; want the resized fold", r, ok)
+	}
+	if _, ok := f.Closed(8); ok {
+		t.Error("Expected Sync to drop a fold with no region")
+	}
+}
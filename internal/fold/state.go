@@ -0,0 +1,129 @@
+package fold
+
+import "sort"
+
+// Folds is the set of closed regions of one buffer. Closed regions may
+// nest; lines are hidden by the outermost one.
+type Folds struct {
+	closed []Region // sorted by start line
+}
+
+// Any reports whether some region is closed.
+func (f *Folds) Any() bool {
+	return len(f.closed) > 0
+}
+
+// Close folds r.
+func (f *Folds) Close(r Region) {
+	i := sort.Search(len(f.closed), func(i int) bool { return f.closed[i].Start >= r.Start })
+	if i < len(f.closed) && f.closed[i].Start == r.Start {
+		f.closed[i] = r
+		return
+	}
+	f.closed = append(f.closed, Region{})
+	copy(f.closed[i+1:], f.closed[i:])
+	f.closed[i] = r
+}
+
+// Open unfolds the region starting on line y, reporting whether there was
+// one.
+func (f *Folds) Open(y int) bool {
+	for i, r := range f.closed {
+		if r.Start == y {
+			f.closed = append(f.closed[:i], f.closed[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// OpenAll unfolds everything.
+func (f *Folds) OpenAll() {
+	f.closed = nil
+}
+
+// Closed returns the closed region starting on line y.
+func (f *Folds) Closed(y int) (Region, bool) {
+	i := sort.Search(len(f.closed), func(i int) bool { return f.closed[i].Start >= y })
+	if i < len(f.closed) && f.closed[i].Start == y {
+		return f.closed[i], true
+	}
+	return Region{}, false
+}
+
+// Hidden returns the outermost closed region hiding line y. A region's
+// own start line is not hidden by it.
+func (f *Folds) Hidden(y int) (Region, bool) {
+	for _, r := range f.closed {
+		if r.Start >= y {
+			break
+		}
+		if y <= r.End {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// Next returns the first visible line after y, skipping a closed region
+// that starts on y.
+func (f *Folds) Next(y int) int {
+	n := y + 1
+	if r, ok := f.Closed(y); ok {
+		n = r.End + 1
+	}
+	for {
+		r, ok := f.Hidden(n)
+		if !ok {
+			return n
+		}
+		n = r.End + 1
+	}
+}
+
+// Prev returns the first visible line before y.
+func (f *Folds) Prev(y int) int {
+	p := y - 1
+	for {
+		r, ok := f.Hidden(p)
+		if !ok {
+			return p
+		}
+		p = r.Start
+	}
+}
+
+// Reveal opens every closed region hiding line y.
+func (f *Folds) Reveal(y int) {
+	kept := f.closed[:0]
+	for _, r := range f.closed {
+		if r.Start >= y || y > r.End {
+			kept = append(kept, r)
+		}
+	}
+	f.closed = kept
+}
+
+// Shift moves closed regions starting after line y by delta lines, to
+// follow lines inserted or deleted at y.
+func (f *Folds) Shift(y, delta int) {
+	for i := range f.closed {
+		if f.closed[i].Start > y {
+			f.closed[i].Start += delta
+			f.closed[i].End += delta
+		}
+	}
+}
+
+// Sync matches closed regions to freshly computed ones: each takes the
+// new extent of the region starting on its line, or is dropped if there
+// is none.
+func (f *Folds) Sync(regions Regions) {
+	kept := f.closed[:0]
+	for _, c := range f.closed {
+		if r, ok := regions.Starting(c.Start); ok {
+			kept = append(kept, r)
+		}
+	}
+	f.closed = kept
+}
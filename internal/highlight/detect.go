@@ -0,0 +1,181 @@
+package highlight
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+const (
+	// modelineLines is how many lines at each end of a file are searched
+	// for an editor modeline.
+	modelineLines = 5
+	// analyseLines bounds the content handed to chroma's analysers, which
+	// only need a sample.
+	analyseLines = 200
+)
+
+var (
+	// vim: ft=go, vim: set filetype=go:, vi: ... and ex: ...
+	vimModeline = regexp.MustCompile(`\b(?:vim?|ex):.*\b(?:ft|filetype|syntax)=([\w+-]+)`)
+	// -*- mode: python -*- or -*- python -*-
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*\bmode:\s*)?([\w+-]+)\s*(?:;.*)?-\*-`)
+)
+
+// interpreters maps shebang interpreters that are not chroma aliases.
+var interpreters = map[string]string{
+	"node":   "javascript",
+	"nodejs": "javascript",
+	"deno":   "typescript",
+	"pwsh":   "powershell",
+	"runghc": "haskell",
+}
+
+// DetectLanguage picks a chroma lexer name for a file, trying in turn
+// config glob mappings (pattern -> language), a modeline, the file name, a
+// shebang and finally content analysis. It returns "" when nothing matches.
+func DetectLanguage(filePath string, lines []string, globs map[string]string) string {
+	if lexer := detectLexer(filePath, lines, globs); lexer != nil {
+		return lexer.Config().Name
+	}
+	return ""
+}
+
+func detectLexer(filePath string, lines []string, globs map[string]string) chroma.Lexer {
+	if lexer := matchGlobs(filePath, globs); lexer != nil {
+		return lexer
+	}
+	if name := modeline(lines); name != "" {
+		if lexer := lexers.Get(name); lexer != nil {
+			return lexer
+		}
+	}
+
+	if filePath != "" {
+		if lexer := lexers.Match(filePath); lexer != nil {
+			return lexer
+		}
+		// Dockerfile.prod, config.yaml.orig: retry without the last suffix
+		if ext := filepath.Ext(filePath); ext != "" {
+			if lexer := lexers.Match(strings.TrimSuffix(filePath, ext)); lexer != nil {
+				return lexer
+			}
+		}
+	}
+
+	if len(lines) > 0 {
+		if name := shebang(lines[0]); name != "" {
+			if lexer := lexers.Get(name); lexer != nil {
+				return lexer
+			}
+		}
+	}
+
+	sample := strings.Join(lines[:min(len(lines), analyseLines)], "\n")
+	if strings.TrimSpace(sample) == "" {
+		return nil
+	}
+	return lexers.Analyse(sample)
+}
+
+// matchGlobs checks config mappings against the file's base name and full
+// path; longer patterns win so specific entries beat catch-alls.
+func matchGlobs(filePath string, globs map[string]string) chroma.Lexer {
+	if filePath == "" || len(globs) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, 0, len(globs))
+	for pattern := range globs {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool { return len(patterns[i]) > len(patterns[j]) })
+
+	base := filepath.Base(filePath)
+	for _, pattern := range patterns {
+		matchBase, _ := filepath.Match(pattern, base)
+		matchPath, _ := filepath.Match(pattern, filePath)
+		if matchBase || matchPath {
+			return lexers.Get(globs[pattern])
+		}
+	}
+	return nil
+}
+
+// modeline returns the file type named by a vim or emacs modeline near the
+// start or end of the file.
+func modeline(lines []string) string {
+	candidates := lines
+	if len(lines) > 2*modelineLines {
+		candidates = append(append([]string(nil), lines[:modelineLines]...), lines[len(lines)-modelineLines:]...)
+	}
+
+	for _, line := range candidates {
+		if m := vimModeline.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+		if m := emacsModeline.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// shebang returns the interpreter named on a "#!" first line, e.g.
+// "python" for "#!/usr/bin/env python3.11".
+func shebang(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	name := filepath.Base(fields[0])
+	if name == "env" {
+		// Skip env's own flags, e.g. "env -S deno run"
+		name = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				name = f
+				break
+			}
+		}
+	}
+
+	if lang, ok := interpreters[name]; ok {
+		return lang
+	}
+	if lexers.Get(name) == nil {
+		// python3.11 -> python
+		name = strings.TrimRight(name, "0123456789.")
+	}
+	return name
+}
+
+// lexerFor returns the chroma lexer called name, falling back to plain
+// text.
+func lexerFor(name string) chroma.Lexer {
+	lexer := lexers.Get(name)
+	if lexer == nil || name == "" {
+		lexer = lexers.Fallback
+	}
+	return chroma.Coalesce(lexer)
+}
+
+// LanguageName returns the canonical name for a chroma language name or
+// alias, e.g. "Go" for "golang", or "" if there is no such language.
+func LanguageName(name string) string {
+	if name == "" {
+		return ""
+	}
+	if lexer := lexers.Get(name); lexer != nil {
+		return lexer.Config().Name
+	}
+	return ""
+}
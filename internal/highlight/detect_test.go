@@ -0,0 +1,54 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justynroberts/finpup/pkg/themes"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		text  string
+		globs map[string]string
+		want  string
+	}{
+		{"extension", "main.go", "", nil, "Go"},
+		{"extra suffix", "Dockerfile.prod", "FROM alpine\n", nil, "Docker"},
+		{"shebang", "deploy", "#!/bin/bash\necho hi\n", nil, "Bash"},
+		{"env shebang with version", "tool", "#!/usr/bin/env python3.11\nprint(1)\n", nil, "Python"},
+		{"node shebang", "serve", "#!/usr/bin/env node\nconsole.log(1)\n", nil, "JavaScript"},
+		{"vim modeline", "notes", "key: value\n# vim: set ft=yaml:\n", nil, "YAML"},
+		{"emacs modeline", "build", "# -*- mode: ruby -*-\nputs 1\n", nil, "Ruby"},
+		{"modeline beats extension", "data.txt", "// vim: ft=go\npackage x\n", nil, "Go"},
+		{"glob beats extension", "app.conf", "", map[string]string{"*.conf": "nginx"}, "Nginx configuration file"},
+		{"glob on full path", "deploy/Jenkinsfile", "", map[string]string{"deploy/*": "groovy"}, "Groovy"},
+		{"content analysis", "", "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n", nil, "Go"},
+		{"nothing to go on", "", "", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := strings.Split(tt.text, "\n")
+			if got := DetectLanguage(tt.path, lines, tt.globs); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	h := New("", themes.Dark, "")
+	if h.Language() != "" {
+		t.Errorf("Expected no language, got %q", h.Language())
+	}
+
+	if !h.SetLanguage("py") || h.Language() != "Python" {
+		t.Errorf("Expected the py alias to select Python, got %q", h.Language())
+	}
+	if h.SetLanguage("no-such-language") || h.Language() != "Python" {
+		t.Errorf("Expected an unknown name to keep Python, got %q", h.Language())
+	}
+}
@@ -0,0 +1,283 @@
+package highlight
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+const (
+	// restartContext is how many unchanged lines above an edit are
+	// re-tokenised to check that the restart point was in a neutral state.
+	restartContext = 16
+	// convergeLines is how many consecutive lines after an edit must match
+	// the cache before the rest of the cache is reused.
+	convergeLines = 2
+)
+
+// span is a run of bytes on one line with a single token type.
+type span struct {
+	typ chroma.TokenType
+	n   int
+}
+
+// lineTokens is the cached tokenisation of one line. eol is the type of
+// the token that carries the line's newline, which is the lexer state the
+// next line starts in as far as we can tell from outside chroma.
+type lineTokens struct {
+	text  string
+	spans []span
+	eol   chroma.TokenType
+}
+
+func (l *lineTokens) equal(o *lineTokens) bool {
+	if l.eol != o.eol || len(l.spans) != len(o.spans) {
+		return false
+	}
+	for i := range l.spans {
+		if l.spans[i] != o.spans[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *lineTokens) hasError() bool {
+	for _, s := range l.spans {
+		if s.typ == chroma.Error {
+			return true
+		}
+	}
+	return false
+}
+
+// neutral reports whether a line starting after a newline of type t is
+// likely to start in the lexer's root state, i.e. not inside a comment,
+// string or other multi-line token.
+func neutral(t chroma.TokenType) bool {
+	return t == chroma.Text || t == chroma.TextWhitespace || t == chroma.Punctuation
+}
+
+// Update brings the per-line token cache in line with lines, re-tokenising
+// only from shortly before the first changed line until the lexer output
+// converges with the cache again. Lexer state carries across lines, so
+// block comments and multi-line strings are highlighted correctly.
+func (h *Highlighter) Update(lines []string) {
+	old := h.doc
+	if len(lines) == 0 {
+		h.doc = nil
+		return
+	}
+	if len(old) == 0 {
+		h.doc = h.tokenise(lines, 0, nil, 0, 0)
+		return
+	}
+
+	// Common prefix and suffix between the cache and the new lines
+	first := 0
+	for first < len(old) && first < len(lines) && old[first].text == lines[first] {
+		first++
+	}
+	if first == len(old) && first == len(lines) {
+		return
+	}
+	suffix := 0
+	for suffix < len(old)-first && suffix < len(lines)-first &&
+		old[len(old)-1-suffix].text == lines[len(lines)-1-suffix] {
+		suffix++
+	}
+
+	// chroma's patterns may look ahead across lines, so an unmatched
+	// opener earlier on (lexed as an Error token) can start matching once
+	// its closer is typed. Re-lex from the earliest such line.
+	limit := first
+	for y := 0; y < first; y++ {
+		if old[y].hasError() {
+			limit = y
+			break
+		}
+	}
+
+	back := restartContext
+	for {
+		restart := max(min(first-back, limit), 0)
+		for restart > 0 && !neutral(old[restart-1].eol) {
+			restart--
+		}
+		if doc := h.tokenise(lines, restart, old, first, len(lines)-suffix); doc != nil {
+			h.doc = doc
+			return
+		}
+		back *= 2
+	}
+}
+
+// tokenise lexes lines from restart onwards. Lines before first must
+// reproduce the cache, otherwise the restart point was inside a multi-line
+// construct and nil is returned. Once editEnd is passed, lexing stops as
+// soon as convergeLines lines match the cache and the remaining cached
+// lines are reused.
+func (h *Highlighter) tokenise(lines []string, restart int, old []lineTokens, first, editEnd int) []lineTokens {
+	doc := make([]lineTokens, restart, len(lines))
+	copy(doc, old[:restart])
+
+	var text strings.Builder
+	for _, line := range lines[restart:] {
+		text.WriteString(line)
+		text.WriteByte('\n')
+	}
+
+	// delta maps a new line index past the edit to its old index
+	delta := len(old) - len(lines)
+	matched := 0
+
+	cur := lineTokens{text: lines[restart]}
+	y := restart
+	finish := func() bool {
+		if y < first && restart > 0 && !cur.equal(&old[y]) {
+			return false
+		}
+		// A line wholly inside a multi-line token matches whenever the token
+		// type does, so convergence has to begin on a line that starts in
+		// the same neutral state in both
+		startsSame := y > 0 && y+delta > 0 && neutral(doc[y-1].eol) && doc[y-1].eol == old[y+delta-1].eol
+		doc = append(doc, cur)
+		if y >= editEnd && old != nil && y+delta < len(old) && cur.equal(&old[y+delta]) &&
+			(matched > 0 || startsSame) {
+			matched++
+		} else {
+			matched = 0
+		}
+		y++
+		if y < len(lines) {
+			cur = lineTokens{text: lines[y]}
+		}
+		return true
+	}
+
+	iterator, err := h.lexer.Tokenise(nil, text.String())
+	if err != nil {
+		return plainLines(lines)
+	}
+	for token := iterator(); token != chroma.EOF && y < len(lines); token = iterator() {
+		value := token.Value
+		for value != "" && y < len(lines) {
+			nl := strings.IndexByte(value, '\n')
+			if nl < 0 {
+				cur.spans = appendSpan(cur.spans, token.Type, len(value))
+				break
+			}
+			if nl > 0 {
+				cur.spans = appendSpan(cur.spans, token.Type, nl)
+			}
+			cur.eol = token.Type
+			if !finish() {
+				return nil
+			}
+			if matched >= convergeLines {
+				return append(doc, old[y+delta:]...)
+			}
+			value = value[nl+1:]
+		}
+	}
+
+	// The lexer may stop early on malformed input; keep the rest plain
+	for y < len(lines) {
+		cur.spans = appendSpan(cur.spans[:0], chroma.Text, len(cur.text))
+		cur.eol = chroma.Text
+		doc = append(doc, cur)
+		y++
+		if y < len(lines) {
+			cur = lineTokens{text: lines[y]}
+		}
+	}
+	return doc
+}
+
+func appendSpan(spans []span, typ chroma.TokenType, n int) []span {
+	if len(spans) > 0 && spans[len(spans)-1].typ == typ {
+		spans[len(spans)-1].n += n
+		return spans
+	}
+	return append(spans, span{typ, n})
+}
+
+func plainLines(lines []string) []lineTokens {
+	doc := make([]lineTokens, len(lines))
+	for i, line := range lines {
+		doc[i] = lineTokens{text: line, spans: []span{{chroma.Text, len(line)}}, eol: chroma.Text}
+	}
+	return doc
+}
+
+// Tokens is an immutable snapshot of the token cache, safe to style from
+// another goroutine while Update runs.
+type Tokens struct {
+	doc []lineTokens
+}
+
+// Tokens returns a snapshot of the cache as of the last Update. Update
+// never modifies a published cache in place.
+func (h *Highlighter) Tokens() Tokens {
+	return Tokens{h.doc}
+}
+
+// Brackets returns the offsets of the bracket characters on line y that
+// are code rather than part of a string or comment. ok is false if the
+// snapshot was taken before the line changed to text.
+func (t Tokens) Brackets(y int, text string) (offsets []int, ok bool) {
+	if y < 0 || y >= len(t.doc) || t.doc[y].text != text {
+		return nil, false
+	}
+
+	offset := 0
+	for _, s := range t.doc[y].spans {
+		end := min(offset+s.n, len(text))
+		if !s.typ.InCategory(chroma.Comment) && !s.typ.InSubCategory(chroma.LiteralString) {
+			for i := offset; i < end; i++ {
+				if strings.IndexByte("()[]{}", text[i]) >= 0 {
+					offsets = append(offsets, i)
+				}
+			}
+		}
+		offset = end
+	}
+	return offsets, true
+}
+
+// Line returns the styled runes of line y as of the last Update.
+func (h *Highlighter) Line(y int) []StyledRune {
+	if y < 0 || y >= len(h.doc) {
+		return nil
+	}
+	return h.styleLine(&h.doc[y])
+}
+
+// StyleLine returns the styled runes of line y from a snapshot, or nil if
+// the snapshot was taken before the line changed to text.
+func (h *Highlighter) StyleLine(t Tokens, y int, text string) []StyledRune {
+	if y < 0 || y >= len(t.doc) || t.doc[y].text != text {
+		return nil
+	}
+	return h.styleLine(&t.doc[y])
+}
+
+func (h *Highlighter) styleLine(line *lineTokens) []StyledRune {
+	result := make([]StyledRune, 0, len(line.text))
+	offset := 0
+	for _, s := range line.spans {
+		style := h.TokenStyle(s.typ)
+		for _, r := range line.text[offset:min(offset+s.n, len(line.text))] {
+			result = append(result, StyledRune{Rune: r, Style: style})
+		}
+		offset += s.n
+	}
+	// Anything the lexer did not cover is drawn plain
+	for offset < len(line.text) {
+		r, size := utf8.DecodeRuneInString(line.text[offset:])
+		result = append(result, StyledRune{Rune: r, Style: h.baseStyle()})
+		offset += size
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package highlight
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/justynroberts/finpup/pkg/themes"
+)
+
+const goSource = `package main
+
+/* a block comment
+   spanning lines */
+func main() {
+	s := ` + "`raw\nstring`" + `
+	x := 1 // trailing
+}
+`
+
+func TestUpdateCarriesStateAcrossLines(t *testing.T) {
+	h := New("go", themes.Dark, "")
+	lines := strings.Split(goSource, "\n")
+	h.Update(lines)
+
+	if typ := h.doc[3].spans[0].typ; !typ.InCategory(chroma.Comment) {
+		t.Errorf("Expected line inside block comment to be a comment, got %v", typ)
+	}
+	if typ := h.doc[6].spans[0].typ; !typ.InSubCategory(chroma.LiteralString) {
+		t.Errorf("Expected second line of raw string to be a string, got %v", typ)
+	}
+
+	// Closing the comment early changes the highlighting of the next line
+	lines = append([]string(nil), lines...)
+	lines[2] = "/* a block comment */"
+	h.Update(lines)
+	if typ := h.doc[3].spans[len(h.doc[3].spans)-1].typ; typ.InCategory(chroma.Comment) {
+		t.Errorf("Expected line after closed comment not to be a comment, got %v", typ)
+	}
+}
+
+func TestUpdateMatchesFullTokenise(t *testing.T) {
+	var base []string
+	for i := 0; i < 40; i++ {
+		base = append(base, strings.Split(goSource, "\n")...)
+	}
+
+	snippets := []string{"/*", "*/", "`", "\"", "x := 2", "", "// c"}
+	rng := rand.New(rand.NewSource(1))
+
+	h := New("go", themes.Dark, "")
+	lines := append([]string(nil), base...)
+	h.Update(lines)
+
+	for i := 0; i < 200; i++ {
+		lines = append([]string(nil), lines...)
+		y := rng.Intn(len(lines))
+		switch rng.Intn(3) {
+		case 0:
+			lines[y] += snippets[rng.Intn(len(snippets))]
+		case 1:
+			lines = append(lines[:y], append([]string{snippets[rng.Intn(len(snippets))]}, lines[y:]...)...)
+		case 2:
+			if len(lines) > 1 {
+				lines = append(lines[:y], lines[y+1:]...)
+			}
+		}
+		h.Update(lines)
+
+		fresh := New("go", themes.Dark, "")
+		fresh.Update(lines)
+		for j := range fresh.doc {
+			if !h.doc[j].equal(&fresh.doc[j]) || h.doc[j].text != fresh.doc[j].text {
+				t.Fatalf("Edit %d: line %d differs from a full tokenise", i, j)
+			}
+		}
+	}
+}
+
+func TestTokensBracketsSkipStringsAndComments(t *testing.T) {
+	h := New("go", themes.Dark, "")
+	lines := []string{`f(")", '[') // {`, `/* ( */ x[0]`}
+	h.Update(lines)
+	tokens := h.Tokens()
+
+	if got, ok := tokens.Brackets(0, lines[0]); !ok || len(got) != 2 || got[0] != 1 || got[1] != 10 {
+		t.Errorf("Brackets(0) = %v, %v; want [1 10], true", got, ok)
+	}
+	if got, _ := tokens.Brackets(1, lines[1]); len(got) != 2 || lines[1][got[0]] != '[' || lines[1][got[1]] != ']' {
+		t.Errorf("Brackets(1) = %v; want the index brackets only", got)
+	}
+	if _, ok := tokens.Brackets(1, "changed"); ok {
+		t.Error("Expected a stale line to report ok == false")
+	}
+}
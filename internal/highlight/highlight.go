@@ -2,7 +2,6 @@ package highlight
 
 import (
 	"bytes"
-	"path/filepath"
 	"strings"
 
 	"github.com/alecthomas/chroma/v2"
@@ -11,66 +10,82 @@ import (
 	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/gdamore/tcell/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/justynroberts/finpup/pkg/themes"
 	"gopkg.in/yaml.v3"
 )
 
 type Highlighter struct {
 	lexer     chroma.Lexer
+	language  string
 	formatter chroma.Formatter
 	style     *chroma.Style
+	theme     themes.Theme
+	// override is set when the config names a chroma style, which then
+	// takes precedence over the theme's token colours.
+	override bool
+	styles   map[chroma.TokenType]tcell.Style
+	depth    themes.ColorDepth
+	// doc caches the tokens of every line; see Update.
+	doc []lineTokens
 }
 
-func New(filePath string) *Highlighter {
-	lexer := lexers.Match(filePath)
-	if lexer == nil {
-		lexer = lexers.Fallback
-	}
-	lexer = chroma.Coalesce(lexer)
-
-	return &Highlighter{
-		lexer:     lexer,
+// New returns a highlighter for language, a chroma lexer name as returned
+// by DetectLanguage; "" or an unknown name highlights nothing.
+func New(language string, theme themes.Theme, syntaxStyle string) *Highlighter {
+	h := &Highlighter{
+		lexer:     lexerFor(language),
+		language:  LanguageName(language),
 		formatter: formatters.TTY256,
-		style:     styles.Get("monokai"),
+		depth:     themes.DepthTrueColor,
 	}
+	h.SetTheme(theme, syntaxStyle)
+	return h
 }
 
-func (h *Highlighter) HighlightLine(line string) ([]StyledRune, error) {
-	iterator, err := h.lexer.Tokenise(nil, line)
-	if err != nil {
-		return runesFromString(line, tcell.ColorWhite), nil
-	}
-
-	var result []StyledRune
-	for token := iterator(); token != chroma.EOF; token = iterator() {
-		style := h.style.Get(token.Type)
-		color := tcell.ColorWhite
-
-		if style.Colour.IsSet() {
-			color = tcell.GetColor(style.Colour.String())
+// SetTheme switches token colours to theme. If syntaxStyle names a chroma
+// style it is used for every token instead of the theme's colours; an
+// unknown name falls back to the theme.
+func (h *Highlighter) SetTheme(theme themes.Theme, syntaxStyle string) {
+	h.theme = theme
+	h.override = false
+	h.styles = make(map[chroma.TokenType]tcell.Style)
+	h.style = styles.Get(theme.SyntaxStyle)
+	if syntaxStyle != "" {
+		if style, ok := styles.Registry[strings.ToLower(syntaxStyle)]; ok {
+			h.style = style
+			h.override = true
 		}
+	}
+}
 
-		for _, r := range token.Value {
-			result = append(result, StyledRune{
-				Rune:  r,
-				Color: color,
-			})
-		}
+// SetLanguage switches to the lexer called name, which may be any chroma
+// name or alias; "" switches highlighting off. It reports false, leaving
+// the lexer alone, if there is no such lexer.
+func (h *Highlighter) SetLanguage(name string) bool {
+	if name != "" && lexers.Get(name) == nil {
+		return false
 	}
+	h.lexer = lexerFor(name)
+	h.language = LanguageName(name)
+	h.doc = nil
+	return true
+}
 
-	return result, nil
+// Language returns the name of the current lexer, or "" if there is none.
+func (h *Highlighter) Language() string {
+	return h.language
 }
 
-type StyledRune struct {
-	Rune  rune
-	Color tcell.Color
+// SetColorDepth maps chroma style colours to what the terminal can show.
+// Theme colours are expected to be mapped already.
+func (h *Highlighter) SetColorDepth(depth themes.ColorDepth) {
+	h.depth = depth
+	h.styles = make(map[chroma.TokenType]tcell.Style)
 }
 
-func runesFromString(s string, color tcell.Color) []StyledRune {
-	result := make([]StyledRune, 0, len(s))
-	for _, r := range s {
-		result = append(result, StyledRune{Rune: r, Color: color})
-	}
-	return result
+type StyledRune struct {
+	Rune  rune
+	Style tcell.Style
 }
 
 func FormatJSON(text string) (string, error) {
@@ -137,29 +152,3 @@ func FormatHCL(text string) (string, error) {
 	formatted := hclwrite.Format([]byte(text))
 	return string(formatted), nil
 }
-
-func DetectLanguage(filePath string) string {
-	ext := strings.ToLower(filepath.Ext(filePath))
-	switch ext {
-	case ".go":
-		return "go"
-	case ".py":
-		return "python"
-	case ".js", ".jsx":
-		return "javascript"
-	case ".ts", ".tsx":
-		return "typescript"
-	case ".json":
-		return "json"
-	case ".yaml", ".yml":
-		return "yaml"
-	case ".md":
-		return "markdown"
-	case ".sh", ".bash":
-		return "bash"
-	case ".hcl", ".tf":
-		return "hcl"
-	default:
-		return "text"
-	}
-}
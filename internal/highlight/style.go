@@ -0,0 +1,166 @@
+package highlight
+
+import (
+	"github.com/alecthomas/chroma/v2"
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/pkg/themes"
+)
+
+// Category returns the theme syntax category (see themes.SyntaxTokens) for
+// a chroma token type, or "" for tokens drawn in the plain foreground.
+func Category(tt chroma.TokenType) string {
+	switch {
+	case tt == chroma.CommentPreproc || tt == chroma.CommentPreprocFile || tt == chroma.NameDecorator:
+		return "preprocessor"
+	case tt.InCategory(chroma.Comment):
+		return "comment"
+	case tt == chroma.KeywordType:
+		return "type"
+	case tt == chroma.KeywordConstant:
+		return "constant"
+	case tt.InCategory(chroma.Keyword):
+		return "keyword"
+	case tt == chroma.NameFunction || tt == chroma.NameFunctionMagic:
+		return "function"
+	case tt == chroma.NameClass || tt == chroma.NameNamespace:
+		return "type"
+	case tt == chroma.NameBuiltin || tt == chroma.NameBuiltinPseudo:
+		return "builtin"
+	case tt == chroma.NameConstant:
+		return "constant"
+	case tt == chroma.NameVariable || tt == chroma.NameVariableClass ||
+		tt == chroma.NameVariableGlobal || tt == chroma.NameVariableInstance ||
+		tt == chroma.NameVariableMagic:
+		return "variable"
+	case tt == chroma.NameTag:
+		return "tag"
+	case tt == chroma.NameAttribute:
+		return "attribute"
+	case tt.InSubCategory(chroma.LiteralString):
+		return "string"
+	case tt.InSubCategory(chroma.LiteralNumber):
+		return "number"
+	case tt.InCategory(chroma.Operator):
+		return "operator"
+	case tt.InCategory(chroma.Punctuation):
+		return "punctuation"
+	case tt == chroma.Error:
+		return "error"
+	}
+	return ""
+}
+
+// categoryTypes gives a representative chroma token type per category, so
+// categories found by the structural parser are styled like lexer tokens.
+var categoryTypes = map[string]chroma.TokenType{
+	"keyword":      chroma.Keyword,
+	"type":         chroma.KeywordType,
+	"function":     chroma.NameFunction,
+	"builtin":      chroma.NameBuiltin,
+	"variable":     chroma.NameVariable,
+	"constant":     chroma.NameConstant,
+	"string":       chroma.LiteralString,
+	"number":       chroma.LiteralNumber,
+	"comment":      chroma.Comment,
+	"operator":     chroma.Operator,
+	"punctuation":  chroma.Punctuation,
+	"tag":          chroma.NameTag,
+	"attribute":    chroma.NameAttribute,
+	"preprocessor": chroma.CommentPreproc,
+	"error":        chroma.Error,
+}
+
+// CategoryStyle returns the style for a syntax category (see
+// themes.SyntaxTokens).
+func (h *Highlighter) CategoryStyle(category string) tcell.Style {
+	if tt, ok := categoryTypes[category]; ok {
+		return h.TokenStyle(tt)
+	}
+	return h.baseStyle()
+}
+
+func (h *Highlighter) baseStyle() tcell.Style {
+	return tcell.StyleDefault.
+		Background(h.theme.Background).
+		Foreground(h.theme.Foreground)
+}
+
+// TokenStyle returns the full style for a token type: the theme's colour
+// for its category where the theme has one, otherwise the chroma style's
+// colour, plus the chroma style's background and text attributes.
+func (h *Highlighter) TokenStyle(tt chroma.TokenType) tcell.Style {
+	if style, ok := h.styles[tt]; ok {
+		return style
+	}
+
+	entry := h.style.Get(tt)
+	style := h.baseStyle()
+
+	if h.depth == themes.DepthMono {
+		style = monoStyle(style, Category(tt))
+		h.styles[tt] = style
+		return style
+	}
+
+	if color, ok := h.themeColor(tt); ok && !h.override {
+		style = style.Foreground(color)
+	} else if entry.Colour.IsSet() {
+		style = style.Foreground(h.depth.Map(toColor(entry.Colour)))
+	}
+
+	// Only honour backgrounds that differ from the style's own, so token
+	// boxes stand out while the theme background shows everywhere else.
+	if entry.Background.IsSet() && entry.Background != h.style.Get(chroma.Background).Background {
+		style = style.Background(h.depth.Map(toColor(entry.Background)))
+	}
+
+	style = style.
+		Bold(entry.Bold == chroma.Yes).
+		Italic(entry.Italic == chroma.Yes).
+		Underline(entry.Underline == chroma.Yes)
+
+	h.styles[tt] = style
+	return style
+}
+
+func (h *Highlighter) themeColor(tt chroma.TokenType) (tcell.Color, bool) {
+	category := Category(tt)
+	if category == "" {
+		return tcell.ColorDefault, false
+	}
+	if color, ok := h.theme.Syntax[category]; ok {
+		return color, true
+	}
+
+	switch category {
+	case "keyword":
+		return h.theme.KeywordFG, true
+	case "string":
+		return h.theme.StringFG, true
+	case "comment":
+		return h.theme.CommentFG, true
+	case "number":
+		return h.theme.NumberFG, true
+	}
+	return tcell.ColorDefault, false
+}
+
+// monoStyle distinguishes tokens by attribute alone for terminals without
+// colour.
+func monoStyle(style tcell.Style, category string) tcell.Style {
+	switch category {
+	case "keyword", "type", "preprocessor":
+		return style.Bold(true)
+	case "comment":
+		return style.Dim(true).Italic(true)
+	case "string":
+		return style.Underline(true)
+	case "error":
+		return style.Reverse(true)
+	}
+	return style
+}
+
+func toColor(c chroma.Colour) tcell.Color {
+	return tcell.NewRGBColor(int32(c.Red()), int32(c.Green()), int32(c.Blue()))
+}
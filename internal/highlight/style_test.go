@@ -0,0 +1,55 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/pkg/themes"
+)
+
+func TestCategory(t *testing.T) {
+	tests := map[chroma.TokenType]string{
+		chroma.Keyword:              "keyword",
+		chroma.KeywordType:          "type",
+		chroma.NameFunction:         "function",
+		chroma.LiteralStringDouble:  "string",
+		chroma.LiteralNumberInteger: "number",
+		chroma.CommentSingle:        "comment",
+		chroma.CommentPreproc:       "preprocessor",
+		chroma.Name:                 "",
+	}
+	for tt, want := range tests {
+		if got := Category(tt); got != want {
+			t.Errorf("Category(%v) = %q, want %q", tt, got, want)
+		}
+	}
+}
+
+func TestTokenStyleUsesThemeColours(t *testing.T) {
+	theme := themes.Dark
+	theme.Syntax = map[string]tcell.Color{"function": tcell.NewRGBColor(1, 2, 3)}
+	h := New("go", theme, "")
+
+	fg, _, _ := h.TokenStyle(chroma.Keyword).Decompose()
+	if fg != theme.KeywordFG {
+		t.Errorf("Expected keyword colour %v, got %v", theme.KeywordFG, fg)
+	}
+	fg, _, _ = h.TokenStyle(chroma.NameFunction).Decompose()
+	if fg != tcell.NewRGBColor(1, 2, 3) {
+		t.Errorf("Expected syntax override for functions, got %v", fg)
+	}
+}
+
+func TestTokenStyleChromaOverride(t *testing.T) {
+	h := New("go", themes.Dark, "github")
+
+	fg, _, _ := h.TokenStyle(chroma.Keyword).Decompose()
+	if fg == themes.Dark.KeywordFG {
+		t.Error("Expected the configured chroma style to override theme colours")
+	}
+	_, _, attrs := h.TokenStyle(chroma.Keyword).Decompose()
+	if attrs&tcell.AttrBold == 0 {
+		t.Error("Expected bold keywords from the github style")
+	}
+}
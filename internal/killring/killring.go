@@ -0,0 +1,96 @@
+package killring
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Entry is a piece of copied or cut text. Block entries came from a block
+// selection and are pasted back as a rectangle.
+type Entry struct {
+	Text  string
+	Block bool
+}
+
+// Ring keeps the most recent copies and cuts, newest first.
+type Ring struct {
+	entries []Entry
+	size    int
+}
+
+func New(size int) *Ring {
+	return &Ring{size: size}
+}
+
+// Push records a new entry. Empty text is ignored and pushing the same text
+// twice in a row keeps a single entry.
+func (r *Ring) Push(e Entry) {
+	if e.Text == "" {
+		return
+	}
+	if len(r.entries) > 0 && r.entries[0] == e {
+		return
+	}
+
+	r.entries = append([]Entry{e}, r.entries...)
+	if len(r.entries) > r.size {
+		r.entries = r.entries[:r.size]
+	}
+}
+
+func (r *Ring) Len() int {
+	return len(r.entries)
+}
+
+// Get returns the i-th most recent entry, where 0 is the newest.
+func (r *Ring) Get(i int) (Entry, bool) {
+	if i < 0 || i >= len(r.entries) {
+		return Entry{}, false
+	}
+	return r.entries[i], true
+}
+
+func (r *Ring) Latest() (Entry, bool) {
+	return r.Get(0)
+}
+
+// Entries returns a copy of the ring, newest first.
+func (r *Ring) Entries() []Entry {
+	return append([]Entry(nil), r.entries...)
+}
+
+// Registers are named slots a-z, independent of the ring and the system
+// clipboard. Yanking into an uppercase name appends to the lowercase one.
+type Registers struct {
+	regs map[rune]Entry
+}
+
+func NewRegisters() *Registers {
+	return &Registers{regs: make(map[rune]Entry)}
+}
+
+func ValidName(name rune) bool {
+	return (name >= 'a' && name <= 'z') || (name >= 'A' && name <= 'Z')
+}
+
+func (r *Registers) Set(name rune, e Entry) error {
+	if !ValidName(name) {
+		return fmt.Errorf("invalid register %q (use a-z)", name)
+	}
+
+	if unicode.IsUpper(name) {
+		name = unicode.ToLower(name)
+		if prev, ok := r.regs[name]; ok && prev.Text != "" {
+			e.Text = prev.Text + "\n" + e.Text
+			e.Block = e.Block && prev.Block
+		}
+	}
+
+	r.regs[name] = e
+	return nil
+}
+
+func (r *Registers) Get(name rune) (Entry, bool) {
+	e, ok := r.regs[unicode.ToLower(name)]
+	return e, ok
+}
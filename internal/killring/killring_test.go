@@ -0,0 +1,59 @@
+package killring
+
+import (
+	"testing"
+)
+
+func TestRingPushAndGet(t *testing.T) {
+	r := New(3)
+	r.Push(Entry{Text: "one"})
+	r.Push(Entry{Text: ""})
+	r.Push(Entry{Text: "two"})
+	r.Push(Entry{Text: "two"})
+
+	if r.Len() != 2 {
+		t.Fatalf("Expected 2 entries, got %d", r.Len())
+	}
+
+	if e, _ := r.Latest(); e.Text != "two" {
+		t.Errorf("Expected newest 'two', got '%s'", e.Text)
+	}
+	if e, _ := r.Get(1); e.Text != "one" {
+		t.Errorf("Expected 'one', got '%s'", e.Text)
+	}
+	if _, ok := r.Get(2); ok {
+		t.Error("Expected no entry at index 2")
+	}
+}
+
+func TestRingDropsOldest(t *testing.T) {
+	r := New(2)
+	r.Push(Entry{Text: "a"})
+	r.Push(Entry{Text: "b"})
+	r.Push(Entry{Text: "c"})
+
+	entries := r.Entries()
+	if len(entries) != 2 || entries[0].Text != "c" || entries[1].Text != "b" {
+		t.Errorf("Unexpected entries %v", entries)
+	}
+}
+
+func TestRegisters(t *testing.T) {
+	regs := NewRegisters()
+
+	if err := regs.Set('1', Entry{Text: "x"}); err == nil {
+		t.Error("Expected error for invalid register name")
+	}
+
+	_ = regs.Set('a', Entry{Text: "first"})
+	_ = regs.Set('A', Entry{Text: "second"})
+
+	e, ok := regs.Get('a')
+	if !ok || e.Text != "first\nsecond" {
+		t.Errorf("Expected appended register, got '%s'", e.Text)
+	}
+
+	if _, ok := regs.Get('b'); ok {
+		t.Error("Register b should be empty")
+	}
+}
@@ -0,0 +1,95 @@
+package syntax
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+func parseGo(b *builder) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", b.text, parser.ParseComments)
+	if file == nil {
+		return err
+	}
+	tf := fset.File(file.Pos())
+
+	offset := func(p token.Pos) int {
+		if !p.IsValid() || int(p) < tf.Base() || int(p) > tf.Base()+tf.Size() {
+			return -1
+		}
+		return tf.Offset(p)
+	}
+	mark := func(n ast.Node, category string) {
+		if n != nil {
+			b.token(offset(n.Pos()), offset(n.End()), category)
+		}
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			b.close()
+			return true
+		}
+		start, end := offset(n.Pos()), offset(n.End())
+		if start < 0 || end < start {
+			return false
+		}
+		b.open(strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."), start, end)
+
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			mark(n.Name, "function")
+		case *ast.TypeSpec:
+			mark(n.Name, "type")
+			markGoType(n.Type, mark)
+		case *ast.CallExpr:
+			switch fun := n.Fun.(type) {
+			case *ast.Ident:
+				if _, ok := types.Universe.Lookup(fun.Name).(*types.Builtin); ok {
+					mark(fun, "builtin")
+				} else {
+					mark(fun, "function")
+				}
+			case *ast.SelectorExpr:
+				mark(fun.Sel, "function")
+			}
+		case *ast.Field:
+			markGoType(n.Type, mark)
+		case *ast.ValueSpec:
+			markGoType(n.Type, mark)
+		case *ast.CompositeLit:
+			markGoType(n.Type, mark)
+		}
+		return true
+	})
+
+	return err
+}
+
+// markGoType marks the type names in a type expression, so types can be
+// told apart from values of the same spelling.
+func markGoType(expr ast.Expr, mark func(ast.Node, string)) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		mark(t, "type")
+	case *ast.SelectorExpr:
+		mark(t.Sel, "type")
+	case *ast.StarExpr:
+		markGoType(t.X, mark)
+	case *ast.ArrayType:
+		markGoType(t.Elt, mark)
+	case *ast.MapType:
+		markGoType(t.Key, mark)
+		markGoType(t.Value, mark)
+	case *ast.ChanType:
+		markGoType(t.Value, mark)
+	case *ast.Ellipsis:
+		markGoType(t.Elt, mark)
+	case *ast.IndexExpr:
+		markGoType(t.X, mark)
+	}
+}
@@ -0,0 +1,66 @@
+package syntax
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// hclWalker builds nodes from hclsyntax's walk. The Attributes and Blocks
+// collections are skipped so their members hang directly off the body.
+type hclWalker struct {
+	b *builder
+}
+
+func parseHCL(b *builder) error {
+	file, diags := hclsyntax.ParseConfig([]byte(b.text), "", hcl.InitialPos)
+	if file != nil {
+		if body, ok := file.Body.(*hclsyntax.Body); ok {
+			hclsyntax.Walk(body, &hclWalker{b: b})
+		}
+	}
+	if diags.HasErrors() {
+		return diags
+	}
+	return nil
+}
+
+func (w *hclWalker) skip(node hclsyntax.Node) bool {
+	switch node.(type) {
+	case hclsyntax.Attributes, hclsyntax.Blocks:
+		return true
+	}
+	return false
+}
+
+func (w *hclWalker) Enter(node hclsyntax.Node) hcl.Diagnostics {
+	if w.skip(node) {
+		return nil
+	}
+
+	r := node.Range()
+	w.b.open(strings.TrimPrefix(fmt.Sprintf("%T", node), "*hclsyntax."), r.Start.Byte, r.End.Byte)
+
+	switch n := node.(type) {
+	case *hclsyntax.Block:
+		w.token(n.TypeRange, "keyword")
+	case *hclsyntax.Attribute:
+		w.token(n.NameRange, "attribute")
+	case *hclsyntax.FunctionCallExpr:
+		w.token(n.NameRange, "function")
+	}
+	return nil
+}
+
+func (w *hclWalker) Exit(node hclsyntax.Node) hcl.Diagnostics {
+	if !w.skip(node) {
+		w.b.close()
+	}
+	return nil
+}
+
+func (w *hclWalker) token(r hcl.Range, category string) {
+	w.b.token(r.Start.Byte, r.End.Byte, category)
+}
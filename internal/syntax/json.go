@@ -0,0 +1,169 @@
+package syntax
+
+import (
+	"fmt"
+	"strings"
+)
+
+// jsonParser is a small recursive descent parser that records positions,
+// which encoding/json does not expose.
+type jsonParser struct {
+	b   *builder
+	s   string
+	pos int
+}
+
+func parseJSON(b *builder) error {
+	p := &jsonParser{b: b, s: b.text}
+	b.open("document", 0, len(p.s))
+	defer b.close()
+
+	// Positions after a syntax error are meaningless, so no partial tree
+	if err := p.document(); err != nil {
+		b.root = nil
+		return err
+	}
+	return nil
+}
+
+func (p *jsonParser) document() error {
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		if err := p.value(); err != nil {
+			return err
+		}
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return p.errorf("unexpected %q after value", p.s[p.pos])
+	}
+	return nil
+}
+
+func (p *jsonParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.s[:min(p.pos, len(p.s))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *jsonParser) value() error {
+	if p.pos >= len(p.s) {
+		return p.errorf("unexpected end of input")
+	}
+
+	switch c := p.s[p.pos]; {
+	case c == '{':
+		return p.container("object", '}', true)
+	case c == '[':
+		return p.container("array", ']', false)
+	case c == '"':
+		start := p.pos
+		if err := p.str(); err != nil {
+			return err
+		}
+		p.b.leaf("string", start, p.pos)
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte("+-.eE0123456789", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		p.b.leaf("number", start, p.pos)
+	default:
+		for _, lit := range []string{"true", "false", "null"} {
+			if strings.HasPrefix(p.s[p.pos:], lit) {
+				p.b.leaf("literal", p.pos, p.pos+len(lit))
+				p.pos += len(lit)
+				return nil
+			}
+		}
+		return p.errorf("unexpected %q", c)
+	}
+	return nil
+}
+
+// container parses an object or array whose opening bracket is at pos.
+func (p *jsonParser) container(kind string, closer byte, members bool) error {
+	p.b.open(kind, p.pos, p.pos)
+	defer p.b.close()
+	node := p.b.stack[len(p.b.stack)-1]
+
+	p.pos++
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == closer {
+		p.pos++
+		node.End = p.pos
+		return nil
+	}
+
+	for {
+		p.skipSpace()
+		if members {
+			if err := p.member(); err != nil {
+				return err
+			}
+		} else if err := p.value(); err != nil {
+			return err
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return p.errorf("missing %q", closer)
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case closer:
+			p.pos++
+			node.End = p.pos
+			return nil
+		default:
+			return p.errorf("expected ',' or %q, got %q", closer, p.s[p.pos])
+		}
+	}
+}
+
+func (p *jsonParser) member() error {
+	if p.pos >= len(p.s) || p.s[p.pos] != '"' {
+		return p.errorf("expected a quoted key")
+	}
+
+	p.b.open("member", p.pos, p.pos)
+	defer p.b.close()
+
+	start := p.pos
+	if err := p.str(); err != nil {
+		return err
+	}
+	p.b.leaf("key", start, p.pos)
+	p.b.token(start, p.pos, "attribute")
+
+	p.skipSpace()
+	if p.pos >= len(p.s) || p.s[p.pos] != ':' {
+		return p.errorf("expected ':' after key")
+	}
+	p.pos++
+	p.skipSpace()
+	return p.value()
+}
+
+func (p *jsonParser) str() error {
+	for i := p.pos + 1; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '\\':
+			i++
+		case '\n':
+			p.pos = i
+			return p.errorf("unterminated string")
+		case '"':
+			p.pos = i + 1
+			return nil
+		}
+	}
+	p.pos = len(p.s)
+	return p.errorf("unterminated string")
+}
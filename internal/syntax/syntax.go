@@ -0,0 +1,324 @@
+// Package syntax builds syntax trees for the languages finpup understands
+// structurally (Go, JSON, YAML and HCL). The trees drive more accurate
+// highlighting than lexing alone and selection/navigation by syntax node.
+package syntax
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Node is a syntax tree node. Start and End are byte offsets into the
+// document text with lines joined by "\n".
+type Node struct {
+	Kind     string
+	Start    int
+	End      int
+	Parent   *Node
+	Children []*Node
+}
+
+// Token marks a range the parser can classify more precisely than the
+// lexer, e.g. a function name at its declaration. Category is one of
+// themes.SyntaxTokens.
+type Token struct {
+	Start    int
+	End      int
+	Category string
+}
+
+// LineToken is a Token clipped to one line, in byte offsets of that line.
+type LineToken struct {
+	StartX   int
+	EndX     int
+	Category string
+}
+
+// Tree is a parsed document.
+type Tree struct {
+	Root       *Node
+	lineStarts []int
+	lineTokens map[int][]LineToken
+}
+
+// LanguageFor returns the structural language for a file path, or "" if
+// there is no parser for it.
+func LanguageFor(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return "go"
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".hcl", ".tf", ".tfvars", ".nomad":
+		return "hcl"
+	}
+	return ""
+}
+
+// LanguageForLexer returns the structural language for a chroma lexer
+// name, or "" if there is no parser for it.
+func LanguageForLexer(name string) string {
+	switch strings.ToLower(name) {
+	case "go":
+		return "go"
+	case "json":
+		return "json"
+	case "yaml":
+		return "yaml"
+	case "hcl", "terraform":
+		return "hcl"
+	}
+	return ""
+}
+
+// Parse builds a tree for text in lang. Go and HCL parsers recover from
+// errors, so a tree may be returned along with an error.
+func Parse(lang, text string) (*Tree, error) {
+	b := newBuilder(text)
+
+	var err error
+	switch lang {
+	case "go":
+		err = parseGo(b)
+	case "json":
+		err = parseJSON(b)
+	case "yaml":
+		err = parseYAML(b)
+	case "hcl":
+		err = parseHCL(b)
+	default:
+		return nil, nil
+	}
+	if b.root == nil {
+		return nil, err
+	}
+	return b.finish(), err
+}
+
+// Position converts a document offset to a buffer position.
+func (t *Tree) Position(offset int) (x, y int) {
+	y = sort.Search(len(t.lineStarts), func(i int) bool { return t.lineStarts[i] > offset }) - 1
+	y = max(y, 0)
+	return offset - t.lineStarts[y], y
+}
+
+// Offset converts a buffer position to a document offset.
+func (t *Tree) Offset(x, y int) int {
+	if y >= len(t.lineStarts) {
+		return t.Root.End
+	}
+	return t.lineStarts[y] + x
+}
+
+// LineTokens returns the tokens on line y, in order.
+func (t *Tree) LineTokens(y int) []LineToken {
+	return t.lineTokens[y]
+}
+
+// Smallest returns the deepest node covering [start, end).
+func (t *Tree) Smallest(start, end int) *Node {
+	n := t.Root
+	for {
+		var next *Node
+		for _, c := range n.Children {
+			if c.Start <= start && end <= c.End && c.Start < c.End {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+}
+
+// Expand returns the smallest node strictly larger than [start, end), or
+// nil if the range already covers the whole document.
+func (t *Tree) Expand(start, end int) *Node {
+	n := t.Smallest(start, end)
+	for n != nil && n.Start == start && n.End == end {
+		n = n.Parent
+	}
+	return n
+}
+
+// Outermost returns the largest ancestor of n that starts where n does,
+// e.g. the statement rather than the identifier that begins it.
+func Outermost(n *Node) *Node {
+	for n.Parent != nil && n.Parent.Parent != nil && n.Parent.Start == n.Start {
+		n = n.Parent
+	}
+	return n
+}
+
+// NextSibling returns the sibling after n, climbing to ancestors when n
+// is the last child.
+func NextSibling(n *Node) *Node {
+	for ; n.Parent != nil; n = n.Parent {
+		siblings := n.Parent.Children
+		for i, s := range siblings {
+			if s == n && i+1 < len(siblings) {
+				return siblings[i+1]
+			}
+		}
+	}
+	return nil
+}
+
+// builder assembles a tree from a parser's enter/leave callbacks.
+type builder struct {
+	text   string
+	root   *Node
+	stack  []*Node
+	tokens []Token
+}
+
+func newBuilder(text string) *builder {
+	return &builder{text: text}
+}
+
+func (b *builder) open(kind string, start, end int) {
+	n := &Node{Kind: kind, Start: start, End: end}
+	if len(b.stack) == 0 {
+		if b.root == nil {
+			b.root = n
+		} else {
+			n.Parent = b.root
+			b.root.Children = append(b.root.Children, n)
+		}
+	} else {
+		parent := b.stack[len(b.stack)-1]
+		n.Parent = parent
+		parent.Children = append(parent.Children, n)
+	}
+	b.stack = append(b.stack, n)
+}
+
+func (b *builder) close() {
+	b.stack = b.stack[:len(b.stack)-1]
+}
+
+func (b *builder) leaf(kind string, start, end int) {
+	b.open(kind, start, end)
+	b.close()
+}
+
+func (b *builder) token(start, end int, category string) {
+	if start >= 0 && start < end {
+		b.tokens = append(b.tokens, Token{start, end, category})
+	}
+}
+
+// finish orders children by position, grows parents to cover their
+// children, and indexes tokens by line.
+func (b *builder) finish() *Tree {
+	var fix func(n *Node)
+	fix = func(n *Node) {
+		sort.SliceStable(n.Children, func(i, j int) bool { return n.Children[i].Start < n.Children[j].Start })
+		for _, c := range n.Children {
+			fix(c)
+			n.Start = min(n.Start, c.Start)
+			n.End = max(n.End, c.End)
+		}
+	}
+	fix(b.root)
+	b.root.Start, b.root.End = 0, len(b.text)
+
+	t := &Tree{Root: b.root, lineStarts: []int{0}, lineTokens: make(map[int][]LineToken)}
+	for i := 0; i < len(b.text); i++ {
+		if b.text[i] == '\n' {
+			t.lineStarts = append(t.lineStarts, i+1)
+		}
+	}
+
+	sort.SliceStable(b.tokens, func(i, j int) bool { return b.tokens[i].Start < b.tokens[j].Start })
+	for _, tok := range b.tokens {
+		startX, startY := t.Position(tok.Start)
+		endX, endY := t.Position(tok.End)
+		for y := startY; y <= endY; y++ {
+			from, to := 0, t.lineEnd(y)-t.lineStarts[y]
+			if y == startY {
+				from = startX
+			}
+			if y == endY {
+				to = endX
+			}
+			if from < to {
+				t.lineTokens[y] = append(t.lineTokens[y], LineToken{from, to, tok.Category})
+			}
+		}
+	}
+	return t
+}
+
+func (t *Tree) lineEnd(y int) int {
+	if y+1 < len(t.lineStarts) {
+		return t.lineStarts[y+1] - 1
+	}
+	return t.Root.End
+}
+
+// Document keeps a tree in step with a buffer's lines.
+type Document struct {
+	lang  string
+	lines []string
+	tree  *Tree
+	err   error
+}
+
+// NewDocument returns a document for the file at path. Files without a
+// structural parser never get a tree.
+func NewDocument(path string) *Document {
+	return &Document{lang: LanguageFor(path)}
+}
+
+// SetLanguage switches the parser, e.g. when the file type changes.
+func (d *Document) SetLanguage(lang string) {
+	if lang != d.lang {
+		d.lang = lang
+		d.lines = nil
+		d.tree, d.err = nil, nil
+	}
+}
+
+func (d *Document) Language() string {
+	return d.lang
+}
+
+// Update reparses the document if lines differ from the last parse. The
+// whole text is parsed again on purpose rather than just the edited lines:
+// it runs on the background worker, so typing never waits for it, and a
+// fresh parse never leaves stale nodes spanning the edit.
+func (d *Document) Update(lines []string) {
+	if d.lang == "" || d.unchanged(lines) {
+		return
+	}
+	d.lines = append(d.lines[:0], lines...)
+	d.tree, d.err = Parse(d.lang, strings.Join(lines, "\n"))
+}
+
+func (d *Document) unchanged(lines []string) bool {
+	if d.lines == nil || len(lines) != len(d.lines) {
+		return false
+	}
+	for i := range lines {
+		if lines[i] != d.lines[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Tree returns the latest tree, or nil if there is none.
+func (d *Document) Tree() *Tree {
+	return d.tree
+}
+
+// Err returns the error from the latest parse.
+func (d *Document) Err() error {
+	return d.err
+}
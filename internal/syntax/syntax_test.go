@@ -0,0 +1,117 @@
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, lang, text string) *Tree {
+	t.Helper()
+	tree, err := Parse(lang, text)
+	if err != nil {
+		t.Fatalf("Parse(%s) failed: %v", lang, err)
+	}
+	return tree
+}
+
+// expandText returns the text selected by expanding from the first
+// occurrence of needle.
+func expandText(tree *Tree, text, needle string) string {
+	start := strings.Index(text, needle)
+	n := tree.Expand(start, start+len(needle))
+	return text[n.Start:n.End]
+}
+
+func TestGoTree(t *testing.T) {
+	text := "package main\n\ntype T struct{ n int }\n\nfunc main() {\n\tfmt.Println(len(\"x\"))\n}\n"
+	tree := parse(t, "go", text)
+
+	if got := expandText(tree, text, "\"x\""); got != "len(\"x\")" {
+		t.Errorf("Expected expansion to the call, got %q", got)
+	}
+	if got := expandText(tree, text, "fmt.Println(len(\"x\"))"); got != "{\n\tfmt.Println(len(\"x\"))\n}" {
+		t.Errorf("Expected expansion to the block, got %q", got)
+	}
+
+	categories := map[string]string{}
+	for y := 0; y < 7; y++ {
+		line := strings.Split(text, "\n")[y]
+		for _, tok := range tree.LineTokens(y) {
+			categories[line[tok.StartX:tok.EndX]] = tok.Category
+		}
+	}
+	for name, want := range map[string]string{"main": "function", "Println": "function", "len": "builtin", "T": "type", "int": "type"} {
+		if categories[name] != want {
+			t.Errorf("Expected %s to be a %s, got %q", name, want, categories[name])
+		}
+	}
+}
+
+func TestJSONTree(t *testing.T) {
+	text := "{\n  \"a\": [1, {\"b\": true}],\n  \"c\": \"d\"\n}"
+	tree := parse(t, "json", text)
+
+	if got := expandText(tree, text, "true"); got != "\"b\": true" {
+		t.Errorf("Expected the member, got %q", got)
+	}
+	if got := expandText(tree, text, "\"b\": true"); got != "{\"b\": true}" {
+		t.Errorf("Expected the object, got %q", got)
+	}
+	if tokens := tree.LineTokens(2); len(tokens) != 1 || tokens[0].Category != "attribute" {
+		t.Errorf("Expected key token on line 3, got %v", tokens)
+	}
+
+	if _, err := Parse("json", "{\"a\": }"); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected a positioned error, got %v", err)
+	}
+}
+
+func TestYAMLTree(t *testing.T) {
+	text := "server:\n  host: \"localhost\"\n  ports: [80, 443]\nnote: |\n  multi\n  line\nother: x # comment\n"
+	tree := parse(t, "yaml", text)
+
+	if got := expandText(tree, text, "\"localhost\""); got != "host: \"localhost\"" {
+		t.Errorf("Expected the pair, got %q", got)
+	}
+	if got := expandText(tree, text, "443"); got != "[80, 443]" {
+		t.Errorf("Expected the flow sequence, got %q", got)
+	}
+	if got := expandText(tree, text, "multi"); got != "|\n  multi\n  line" {
+		t.Errorf("Expected the block scalar, got %q", got)
+	}
+	if got := expandText(tree, text, "x"); got != "other: x" {
+		t.Errorf("Expected the pair without comment, got %q", got)
+	}
+}
+
+func TestHCLTree(t *testing.T) {
+	text := "resource \"a\" \"b\" {\n  name = upper(\"x\")\n  count = 2\n}\n"
+	tree := parse(t, "hcl", text)
+
+	if got := expandText(tree, text, "\"x\""); got != "upper(\"x\")" {
+		t.Errorf("Expected the call, got %q", got)
+	}
+
+	start := strings.Index(text, "name")
+	n := Outermost(tree.Smallest(start, start))
+	next := NextSibling(n)
+	if next == nil || text[next.Start:next.End] != "count = 2" {
+		t.Errorf("Expected next sibling to be the count attribute, got %v", next)
+	}
+}
+
+func TestDocumentUpdate(t *testing.T) {
+	d := NewDocument("x.json")
+	d.Update([]string{"[1,"})
+	if d.Tree() != nil || d.Err() == nil {
+		t.Error("Expected no tree for invalid JSON")
+	}
+	d.Update([]string{"[1,", "2]"})
+	if d.Tree() == nil || d.Err() != nil {
+		t.Errorf("Expected a tree after fixing the JSON, got error %v", d.Err())
+	}
+
+	if NewDocument("notes.txt").Language() != "" {
+		t.Error("Expected no structural language for .txt")
+	}
+}
@@ -0,0 +1,195 @@
+package syntax
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlBuilder converts a yaml.v3 node tree, which only records where each
+// node starts, into ranges by scanning the source for each scalar's end.
+type yamlBuilder struct {
+	b     *builder
+	lines []string
+	// starts holds the offset of each line in the joined text
+	starts []int
+}
+
+func parseYAML(b *builder) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(b.text), &doc); err != nil {
+		return err
+	}
+
+	y := &yamlBuilder{b: b, lines: strings.Split(b.text, "\n")}
+	offset := 0
+	for _, line := range y.lines {
+		y.starts = append(y.starts, offset)
+		offset += len(line) + 1
+	}
+
+	b.open("document", 0, len(b.text))
+	for _, n := range doc.Content {
+		y.node(n, false)
+	}
+	b.close()
+	return nil
+}
+
+// offset converts a yaml.v3 line/column (1-based, column in runes).
+func (y *yamlBuilder) offset(n *yaml.Node) int {
+	line := min(max(n.Line-1, 0), len(y.lines)-1)
+	x := 0
+	for i := 1; i < n.Column && x < len(y.lines[line]); i++ {
+		_, size := utf8.DecodeRuneInString(y.lines[line][x:])
+		x += size
+	}
+	return y.starts[line] + x
+}
+
+func (y *yamlBuilder) node(n *yaml.Node, inFlow bool) int {
+	start := y.offset(n)
+	flow := inFlow || n.Style&yaml.FlowStyle != 0
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		y.b.open("mapping", start, start)
+		node := y.b.stack[len(y.b.stack)-1]
+		end := start
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			y.b.open("pair", y.offset(key), y.offset(key))
+			keyEnd := y.node(key, flow)
+			y.b.token(y.offset(key), keyEnd, "attribute")
+			end = max(end, keyEnd, y.node(value, flow))
+			y.b.close()
+		}
+		node.End = y.closeFlow(n, end, '}')
+		y.b.close()
+		return node.End
+
+	case yaml.SequenceNode:
+		y.b.open("sequence", start, start)
+		node := y.b.stack[len(y.b.stack)-1]
+		end := start
+		for _, item := range n.Content {
+			end = max(end, y.node(item, flow))
+		}
+		node.End = y.closeFlow(n, end, ']')
+		y.b.close()
+		return node.End
+
+	case yaml.AliasNode:
+		end := start + 1 + len(n.Value)
+		y.b.leaf("alias", start, end)
+		return end
+	}
+
+	end := y.scalarEnd(n, start, flow)
+	y.b.leaf("scalar", start, end)
+	return end
+}
+
+// closeFlow extends a flow collection's end past its closing bracket.
+func (y *yamlBuilder) closeFlow(n *yaml.Node, end int, closer byte) int {
+	if n.Style&yaml.FlowStyle == 0 {
+		return end
+	}
+	if i := strings.IndexByte(y.b.text[end:], closer); i >= 0 {
+		return end + i + 1
+	}
+	return end
+}
+
+func (y *yamlBuilder) scalarEnd(n *yaml.Node, start int, flow bool) int {
+	text := y.b.text
+
+	// Skip a tag or anchor in front of the value
+	for start < len(text) && (text[start] == '!' || text[start] == '&') {
+		for start < len(text) && text[start] != ' ' && text[start] != '\n' {
+			start++
+		}
+		for start < len(text) && text[start] == ' ' {
+			start++
+		}
+	}
+	if start >= len(text) {
+		return len(text)
+	}
+
+	switch {
+	case n.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < len(text); i++ {
+			if text[i] == '\\' {
+				i++
+			} else if text[i] == '"' {
+				return i + 1
+			}
+		}
+		return len(text)
+
+	case n.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < len(text); i++ {
+			if text[i] == '\'' {
+				if i+1 < len(text) && text[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+		return len(text)
+
+	case n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		return y.blockEnd(start)
+	}
+
+	// Plain scalar: up to a comment, the end of the line or, in flow
+	// context, a flow indicator
+	end := start
+	for end < len(text) && text[end] != '\n' {
+		if text[end] == '#' && end > start && text[end-1] == ' ' {
+			break
+		}
+		if flow && strings.IndexByte(",]}", text[end]) >= 0 {
+			break
+		}
+		if !flow && text[end] == ':' && (end+1 == len(text) || text[end+1] == ' ' || text[end+1] == '\n') {
+			break
+		}
+		end++
+	}
+	for end > start && text[end-1] == ' ' {
+		end--
+	}
+	return end
+}
+
+// blockEnd finds the end of a literal or folded block scalar whose
+// indicator is at start: the last non-blank line indented deeper than the
+// line holding the indicator.
+func (y *yamlBuilder) blockEnd(start int) int {
+	first := 0
+	for first+1 < len(y.starts) && y.starts[first+1] <= start {
+		first++
+	}
+	indent := indentOf(y.lines[first])
+	end := y.starts[first] + len(y.lines[first])
+
+	for i := first + 1; i < len(y.lines); i++ {
+		line := y.lines[i]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indentOf(line) <= indent {
+			break
+		}
+		end = y.starts[i] + len(line)
+	}
+	return end
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
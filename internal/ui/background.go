@@ -0,0 +1,214 @@
+package ui
+
+import (
+	"sync"
+
+	"github.com/justynroberts/finpup/internal/fold"
+	"github.com/justynroberts/finpup/internal/highlight"
+	"github.com/justynroberts/finpup/internal/syntax"
+)
+
+// background tokenises and parses the buffer, and finds its fold regions,
+// on a worker goroutine so a keystroke never waits for highlighting. Draw
+// submits the current lines and renders whatever results are ready; lines
+// the worker has not caught up with are drawn plain, and notify asks for a
+// redraw once it has.
+type background struct {
+	highlighter *highlight.Highlighter
+	syntax      *syntax.Document
+	notify      func()
+	// tabSize is the width of a tab when folding by indentation.
+	tabSize int
+
+	// workMu is held while the worker uses the highlighter and syntax
+	// document, so SetLanguage can swap their languages safely.
+	workMu sync.Mutex
+
+	mu sync.Mutex
+	// lines and version are the latest submitted text; version only moves
+	// when the text actually changes.
+	lines   []string
+	version int
+	// done is the version the published results were computed from.
+	done    int
+	tokens  highlight.Tokens
+	tree    *syntax.Tree
+	treeErr error
+	regions fold.Regions
+	stopped bool
+	wake    chan struct{}
+	idle    *sync.Cond
+}
+
+func newBackground(h *highlight.Highlighter, doc *syntax.Document, tabSize int, notify func()) *background {
+	b := &background{
+		highlighter: h,
+		syntax:      doc,
+		notify:      notify,
+		tabSize:     tabSize,
+		wake:        make(chan struct{}, 1),
+	}
+	b.idle = sync.NewCond(&b.mu)
+	go b.run()
+	return b
+}
+
+// Submit hands the worker the buffer's current lines. It is cheap when
+// nothing changed: unchanged lines share their string data.
+func (b *background) Submit(lines []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped || (b.lines != nil && sameLines(b.lines, lines)) {
+		return
+	}
+	// The buffer edits Lines in place, so keep a copy of the slice
+	b.lines = append([]string(nil), lines...)
+	b.version++
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func sameLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *background) run() {
+	for range b.wake {
+		b.mu.Lock()
+		lines, version := b.lines, b.version
+		b.mu.Unlock()
+		if version == b.doneVersion() {
+			continue
+		}
+
+		b.workMu.Lock()
+		b.highlighter.Update(lines)
+		tokens := b.highlighter.Tokens()
+		var tree *syntax.Tree
+		var treeErr error
+		if b.syntax != nil {
+			b.syntax.Update(lines)
+			tree, treeErr = b.syntax.Tree(), b.syntax.Err()
+		}
+		b.workMu.Unlock()
+		regions := findRegions(lines, tokens, tree, b.tabSize)
+
+		b.mu.Lock()
+		b.tokens, b.tree, b.treeErr, b.regions = tokens, tree, treeErr, regions
+		b.done = version
+		b.idle.Broadcast()
+		b.mu.Unlock()
+
+		if b.notify != nil {
+			b.notify()
+		}
+	}
+}
+
+// SetLanguage switches the highlighter to lexer and the syntax document to
+// structural, then reprocesses the last submitted lines. It reports false
+// if lexer is unknown.
+func (b *background) SetLanguage(lexer, structural string) bool {
+	b.workMu.Lock()
+	ok := b.highlighter.SetLanguage(lexer)
+	if ok && b.syntax != nil {
+		b.syntax.SetLanguage(structural)
+	}
+	b.workMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	// Forget the lines so the next Submit counts as a change
+	b.mu.Lock()
+	b.lines = nil
+	b.mu.Unlock()
+	return true
+}
+
+// findRegions folds by syntax where there is a tree, and otherwise by
+// indentation and brackets.
+func findRegions(lines []string, tokens highlight.Tokens, tree *syntax.Tree, tabSize int) fold.Regions {
+	if tree != nil {
+		return fold.Tree(tree, lines)
+	}
+	return fold.Merge(
+		fold.Indentation(lines, tabSize),
+		fold.Brackets(lines, func(y int) []int {
+			offsets, _ := tokens.Brackets(y, lines[y])
+			return offsets
+		}),
+	)
+}
+
+func (b *background) doneVersion() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done
+}
+
+// Tokens returns the latest token snapshot, which may lag behind the
+// submitted lines.
+func (b *background) Tokens() highlight.Tokens {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// Tree returns the syntax tree if it matches the submitted lines, so
+// ranges from it line up with the text.
+func (b *background) Tree() *syntax.Tree {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done != b.version {
+		return nil
+	}
+	return b.tree
+}
+
+// Regions returns the latest fold regions, and whether they match the
+// submitted lines.
+func (b *background) Regions() (fold.Regions, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.regions, b.done == b.version
+}
+
+// Wait blocks until the worker has caught up with the submitted lines.
+func (b *background) Wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.done != b.version && !b.stopped {
+		b.idle.Wait()
+	}
+}
+
+// TreeError returns the error from the latest parse.
+func (b *background) TreeError() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.treeErr
+}
+
+// Stop ends the worker.
+func (b *background) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.stopped {
+		b.stopped = true
+		close(b.wake)
+		b.idle.Broadcast()
+	}
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/highlight"
+)
+
+const (
+	openBrackets  = "([{"
+	closeBrackets = ")]}"
+	// matchScan is how many lines either way a partner is looked for.
+	matchScan = 1000
+)
+
+// bracketMatch is a bracket at the cursor and its partner, as byte offsets.
+type bracketMatch struct {
+	ok       bool
+	y, x     int
+	partnerY int
+	partnerX int
+}
+
+// brackets returns the offsets of the code brackets on line y. Until the
+// worker has tokenized the line every bracket counts.
+func (ui *UI) brackets(tokens highlight.Tokens, y int) []int {
+	line := ui.buffer.Lines[y]
+	if offsets, ok := tokens.Brackets(y, line); ok {
+		return offsets
+	}
+	var offsets []int
+	for i := 0; i < len(line); i++ {
+		if strings.IndexByte(openBrackets+closeBrackets, line[i]) >= 0 {
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// findMatch pairs the bracket under the cursor, or failing that the one
+// just before it, with its partner. Brackets in strings and comments are
+// skipped, as are other kinds of bracket in between.
+func (ui *UI) findMatch(tokens highlight.Tokens) bracketMatch {
+	y, x := ui.buffer.CursorY, ui.buffer.CursorX
+	if y >= len(ui.buffer.Lines) {
+		return bracketMatch{}
+	}
+	line := ui.buffer.Lines[y]
+	offsets := ui.brackets(tokens, y)
+	at := -1
+	for _, o := range offsets {
+		if o == x {
+			at = o
+			break
+		}
+		if o == x-1 {
+			at = o
+		}
+	}
+	if at < 0 {
+		return bracketMatch{}
+	}
+
+	ch := line[at]
+	dir := 1
+	var partner byte
+	if i := strings.IndexByte(openBrackets, ch); i >= 0 {
+		partner = closeBrackets[i]
+	} else {
+		dir = -1
+		partner = openBrackets[strings.IndexByte(closeBrackets, ch)]
+	}
+
+	depth := 0
+	for ly, n := y, 0; ly >= 0 && ly < len(ui.buffer.Lines) && n <= matchScan; ly, n = ly+dir, n+1 {
+		text := ui.buffer.Lines[ly]
+		if ly != y {
+			offsets = ui.brackets(tokens, ly)
+		}
+		for i := range offsets {
+			o := offsets[i]
+			if dir < 0 {
+				o = offsets[len(offsets)-1-i]
+			}
+			if ly == y && (o-at)*dir <= 0 {
+				continue
+			}
+			switch text[o] {
+			case ch:
+				depth++
+			case partner:
+				if depth == 0 {
+					return bracketMatch{ok: true, y: y, x: at, partnerY: ly, partnerX: o}
+				}
+				depth--
+			}
+		}
+	}
+	return bracketMatch{}
+}
+
+// isMatched reports whether the byte at offset x of line y is one of the
+// matched pair.
+func (ui *UI) isMatched(x, y int) bool {
+	m := ui.match
+	return m.ok && (x == m.x && y == m.y || x == m.partnerX && y == m.partnerY)
+}
+
+// matchStyle marks a matched bracket without hiding its colour.
+func matchStyle(style tcell.Style) tcell.Style {
+	return style.Bold(true).Underline(true)
+}
+
+// matchHint describes the partner of the bracket at the cursor when it is
+// not on screen, so the status bar can show what it closes or opens.
+func (ui *UI) matchHint() string {
+	m := ui.match
+	if !m.ok || m.partnerY == m.y {
+		return ""
+	}
+	line := ui.buffer.Lines[m.partnerY]
+	if _, _, ok := ui.cellAt(m.partnerY, buffer.ColumnAt(line, m.partnerX, ui.tabSize()), ui.height-2); ok {
+		return ""
+	}
+	return fmt.Sprintf("Matches line %d: %s", m.partnerY+1, strings.TrimSpace(line))
+}
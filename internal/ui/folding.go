@@ -0,0 +1,154 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/justynroberts/finpup/internal/fold"
+)
+
+// foldColumnWidth is the width of the fold marker column: one marker and
+// a space. The column is only shown when the buffer has fold regions.
+const foldColumnWidth = 2
+
+const (
+	foldOpenMarker   = '▾'
+	foldClosedMarker = '▸'
+)
+
+// syncFolds keeps closed folds and signs attached to their lines as the
+// text changes, and opens any fold hiding the cursor so it is never lost.
+func (ui *UI) syncFolds() {
+	// Lines inserted or deleted since the last draw were edited at the
+	// cursor as it was then
+	if n := len(ui.buffer.Lines); ui.foldLines != 0 && n != ui.foldLines {
+		ui.folds.Shift(ui.foldCursor, n-ui.foldLines)
+		ui.shiftSigns(ui.foldCursor, n-ui.foldLines)
+	}
+
+	regions, current := ui.bg.Regions()
+	ui.regions = regions
+	if current {
+		ui.folds.Sync(regions)
+	}
+	ui.folds.Reveal(ui.buffer.CursorY)
+	ui.foldLines, ui.foldCursor = len(ui.buffer.Lines), ui.buffer.CursorY
+}
+
+// currentRegions returns fold regions for the buffer's current text,
+// waiting for the worker to catch up.
+func (ui *UI) currentRegions() fold.Regions {
+	ui.bg.Submit(ui.buffer.Lines)
+	ui.bg.Wait()
+	ui.syncFolds()
+	return ui.regions
+}
+
+func (ui *UI) foldWidth() int {
+	if len(ui.regions) == 0 {
+		return 0
+	}
+	return foldColumnWidth
+}
+
+// foldMarker returns the gutter marker for lineNum, or ' ' if no region
+// starts there.
+func (ui *UI) foldMarker(lineNum int) rune {
+	if _, ok := ui.folds.Closed(lineNum); ok {
+		return foldClosedMarker
+	}
+	if _, ok := ui.regions.Starting(lineNum); ok {
+		return foldOpenMarker
+	}
+	return ' '
+}
+
+// foldSummary is drawn after the first line of a closed fold.
+func foldSummary(r fold.Region) string {
+	n := r.End - r.Start
+	if n == 1 {
+		return " ⋯ 1 line "
+	}
+	return fmt.Sprintf(" ⋯ %d lines ", n)
+}
+
+// Folded reports whether a closed fold starts on line y.
+func (ui *UI) Folded(y int) bool {
+	_, ok := ui.folds.Closed(y)
+	return ok
+}
+
+// NextLine returns the first visible line after y, or y if it is the last.
+func (ui *UI) NextLine(y int) int {
+	if n := ui.folds.Next(y); n < len(ui.buffer.Lines) {
+		return n
+	}
+	return y
+}
+
+// PrevLine returns the first visible line before y, or y if it is the
+// first.
+func (ui *UI) PrevLine(y int) int {
+	if p := ui.folds.Prev(y); p >= 0 {
+		return p
+	}
+	return y
+}
+
+// VisibleLine returns y, or the first line of the closed fold hiding it.
+func (ui *UI) VisibleLine(y int) int {
+	return ui.folds.Prev(y + 1)
+}
+
+// ToggleFold opens the closed fold starting on line y, or else closes the
+// innermost region containing y. It returns the region and false if there
+// is nothing to fold there.
+func (ui *UI) ToggleFold(y int) (fold.Region, bool) {
+	regions := ui.currentRegions()
+	if r, ok := ui.folds.Closed(y); ok {
+		ui.folds.Open(y)
+		return r, true
+	}
+
+	// Any other closed region containing y would hide it, so the
+	// innermost one is open
+	if r, ok := regions.Innermost(y); ok {
+		ui.folds.Close(r)
+		return r, true
+	}
+	return fold.Region{}, false
+}
+
+// FoldToLevel shows the first level nesting levels of regions and folds
+// the rest; level 0 folds everything. It returns the number of regions
+// closed.
+func (ui *UI) FoldToLevel(level int) int {
+	regions := ui.currentRegions()
+	ui.folds.OpenAll()
+	n := 0
+	for _, r := range regions {
+		if r.Level > level {
+			ui.folds.Close(r)
+			n++
+		}
+	}
+	return n
+}
+
+// UnfoldAll opens every fold.
+func (ui *UI) UnfoldAll() {
+	ui.folds.OpenAll()
+}
+
+// FoldMarkerAt returns the line whose fold marker is drawn at screen cell
+// (x, y), if any.
+func (ui *UI) FoldMarkerAt(x, y int) (int, bool) {
+	markerX := ui.signWidth() + ui.numberWidth()
+	if ui.foldWidth() == 0 || x != markerX || y < 0 || y >= ui.height-2 {
+		return 0, false
+	}
+	lineNum, r := ui.moveRows(ui.offsetY, ui.offsetRow, y)
+	if r > 0 || ui.foldMarker(lineNum) == ' ' {
+		return 0, false
+	}
+	return lineNum, true
+}
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/justynroberts/finpup/internal/config"
+)
+
+// signColumnWidth is the width of the sign column: one glyph and a space.
+// The column is only shown while some line has a sign.
+const signColumnWidth = 2
+
+// Sign marks a line in the gutter, e.g. a diagnostic, a changed line, a
+// bookmark or a search hit.
+type Sign struct {
+	Line int
+	// Glyph is drawn in the sign column and should be one cell wide.
+	Glyph rune
+	// Category names the theme colour to draw the glyph in, as for syntax
+	// tokens: "error", "string", "keyword" and so on.
+	Category string
+	// Priority decides which sign is shown when several mark one line.
+	Priority int
+	// Message is shown in the status bar while the cursor is on the line
+	// or when the sign is clicked.
+	Message string
+}
+
+// SetSigns replaces the signs registered by source, so each subsystem can
+// update its own markers without touching the others'.
+func (ui *UI) SetSigns(source string, signs []Sign) {
+	if ui.signs == nil {
+		ui.signs = make(map[string][]Sign)
+	}
+	if len(signs) == 0 {
+		delete(ui.signs, source)
+	} else {
+		ui.signs[source] = append([]Sign(nil), signs...)
+	}
+	ui.indexSigns()
+}
+
+// indexSigns keeps the winning sign per line so drawing is a map lookup.
+func (ui *UI) indexSigns() {
+	ui.signLines = make(map[int]Sign)
+	for _, list := range ui.signs {
+		for _, s := range list {
+			if cur, ok := ui.signLines[s.Line]; !ok || s.Priority > cur.Priority {
+				ui.signLines[s.Line] = s
+			}
+		}
+	}
+}
+
+// shiftSigns moves the signs below line y by delta lines, as lines are
+// inserted or deleted there. Signs on deleted lines are dropped.
+func (ui *UI) shiftSigns(y, delta int) {
+	for source, list := range ui.signs {
+		kept := list[:0]
+		for _, s := range list {
+			if s.Line > y {
+				s.Line += delta
+				if s.Line <= y {
+					continue
+				}
+			}
+			kept = append(kept, s)
+		}
+		if len(kept) == 0 {
+			delete(ui.signs, source)
+		} else {
+			ui.signs[source] = kept
+		}
+	}
+	ui.indexSigns()
+}
+
+// ClearSigns removes the signs registered by source.
+func (ui *UI) ClearSigns(source string) {
+	ui.SetSigns(source, nil)
+}
+
+// SignAt returns the highest-priority sign on line.
+func (ui *UI) SignAt(line int) (Sign, bool) {
+	s, ok := ui.signLines[line]
+	return s, ok
+}
+
+// SignAtScreen returns the sign drawn at screen cell (x, y), if any.
+func (ui *UI) SignAtScreen(x, y int) (Sign, bool) {
+	if x >= ui.signWidth() || y < 0 || y >= ui.height-2 {
+		return Sign{}, false
+	}
+	lineNum, r := ui.moveRows(ui.offsetY, ui.offsetRow, y)
+	if r > 0 {
+		// Signs are drawn on a wrapped line's first row only
+		return Sign{}, false
+	}
+	return ui.SignAt(lineNum)
+}
+
+func (ui *UI) signWidth() int {
+	if len(ui.signLines) == 0 {
+		return 0
+	}
+	return signColumnWidth
+}
+
+// lineLabel returns the gutter number for lineNum in the configured mode.
+func (ui *UI) lineLabel(lineNum, width int) string {
+	n := lineNum + 1
+	switch ui.config.Editor.ShowLineNums {
+	case config.LineNumbersRelative:
+		n = abs(lineNum - ui.buffer.CursorY)
+	case config.LineNumbersHybrid:
+		if lineNum != ui.buffer.CursorY {
+			n = abs(lineNum - ui.buffer.CursorY)
+		}
+	}
+	return fmt.Sprintf("%*d ", width-1, n)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
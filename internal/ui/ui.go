@@ -2,80 +2,310 @@ package ui
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+	"github.com/justynroberts/finpup/internal/fold"
 	"github.com/justynroberts/finpup/internal/highlight"
+	"github.com/justynroberts/finpup/internal/syntax"
+	"github.com/justynroberts/finpup/pkg/themes"
 )
 
+// minNumberWidth is the narrowest line-number column, so the gutter does
+// not change width while a new file grows to 100 lines.
+const minNumberWidth = 3
+
 type UI struct {
 	screen      tcell.Screen
 	buffer      *buffer.Buffer
+	config      *config.Config
 	highlighter *highlight.Highlighter
-	offsetY     int
-	width       int
-	height      int
-	statusMsg   string
+	// bg highlights and parses the buffer off the input goroutine.
+	bg *background
+	// signs holds gutter markers by the subsystem that set them, and
+	// signLines the one shown for each line.
+	signs     map[string][]Sign
+	signLines map[int]Sign
+	// folds are the closed fold regions and regions every foldable one.
+	// foldLines and foldCursor are the line count and cursor line at the
+	// last draw, to follow lines inserted or deleted since.
+	folds      fold.Folds
+	regions    fold.Regions
+	foldLines  int
+	foldCursor int
+	// match is the bracket at the cursor and its partner as of this draw.
+	match bracketMatch
+	// language is the highlighter's language; languageSet is true when the
+	// user chose it, so it is not re-detected.
+	language    string
+	languageSet bool
+	theme       themes.Theme
+	// depth is the terminal's colour depth; theme colours are already
+	// mapped to it.
+	depth   themes.ColorDepth
+	offsetY int
+	// offsetX is the first visible display column when lines are not
+	// wrapped.
+	offsetX int
+	// offsetRow is the first visible screen row of line offsetY when it is
+	// soft-wrapped.
+	offsetRow int
+	// wrap enables soft wrap; wrapSet is true once the user toggled it, so
+	// the language default no longer applies.
+	wrap      bool
+	wrapSet   bool
+	width     int
+	height    int
+	statusMsg string
+	// freeScroll is set when the view was scrolled independently of the
+	// cursor (e.g. with the mouse wheel); Draw then leaves offsetY alone.
+	freeScroll bool
+	// helpItems are the help bar entries with their drawn column ranges,
+	// so clicks on the bar can be mapped back to a key.
+	helpItems []helpItem
+}
+
+type helpItem struct {
+	label      string
+	key        tcell.Key
+	start, end int
 }
 
-func New(buf *buffer.Buffer) (*UI, error) {
+func New(buf *buffer.Buffer, cfg *config.Config) (*UI, error) {
+	if depth, forced := themes.ParseColorDepth(cfg.Theme.ColorMode); forced && depth == themes.DepthTrueColor {
+		// tcell only emits 24-bit colour when it believes the terminal
+		// supports it, which terminfo often under-reports
+		defer setEnv("COLORTERM", "truecolor")()
+	}
+
 	screen, err := tcell.NewScreen()
 	if err != nil {
 		return nil, err
 	}
+	return NewWithScreen(screen, buf, cfg)
+}
+
+// setEnv sets an environment variable for as long as tcell needs to see
+// it and returns a func that puts back the previous value, so the setting
+// does not leak into commands run from the editor.
+func setEnv(key, value string) (restore func()) {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
 
+// NewWithScreen sets up the UI on an uninitialised screen, such as a
+// tcell.SimulationScreen in tests.
+func NewWithScreen(screen tcell.Screen, buf *buffer.Buffer, cfg *config.Config) (*UI, error) {
 	if err := screen.Init(); err != nil {
 		return nil, err
 	}
+	// Deliver terminal pastes as one bracketed block instead of keystrokes
+	screen.EnablePaste()
+	screen.EnableMouse(tcell.MouseButtonEvents | tcell.MouseDragEvents)
+
+	depth, forced := themes.ParseColorDepth(cfg.Theme.ColorMode)
+	if !forced {
+		depth = themes.DetectColorDepth(os.Getenv, screen.Colors())
+	}
 
 	width, height := screen.Size()
+	theme := themes.GetTheme(cfg.Theme.Current).WithDepth(depth)
+	language := highlight.DetectLanguage(buf.FilePath, buf.Lines, cfg.Editor.Languages)
 
 	ui := &UI{
 		screen:      screen,
 		buffer:      buf,
-		highlighter: highlight.New(buf.FilePath),
+		config:      cfg,
+		highlighter: highlight.New(language, theme, cfg.Theme.SyntaxStyle),
+		language:    language,
+		theme:       theme,
+		depth:       depth,
 		offsetY:     0,
 		width:       width,
 		height:      height,
 		statusMsg:   "",
+		helpItems: []helpItem{
+			{label: "^S Save", key: tcell.KeyCtrlS},
+			{label: "^Q Quit", key: tcell.KeyCtrlQ},
+			{label: "^K Del", key: tcell.KeyCtrlK},
+			{label: "^Z Undo", key: tcell.KeyCtrlZ},
+			{label: "^T Top", key: tcell.KeyCtrlT},
+			{label: "^B Bottom", key: tcell.KeyCtrlB},
+			{label: "^W Select", key: tcell.KeyCtrlW},
+			{label: "^A AI", key: tcell.KeyCtrlA},
+			{label: "^E Emoji", key: tcell.KeyCtrlE},
+			{label: "^F Format", key: tcell.KeyCtrlF},
+		},
+	}
+
+	ui.highlighter.SetColorDepth(depth)
+	ui.applyWrapDefault()
+	var doc *syntax.Document
+	if cfg.Editor.Structural {
+		doc = syntax.NewDocument(buf.FilePath)
+		doc.SetLanguage(structuralLanguage(language, buf.FilePath))
 	}
+	// Redraw when the worker finishes; the event loop draws after every event
+	ui.bg = newBackground(ui.highlighter, doc, ui.tabSize(), func() {
+		screen.PostEvent(tcell.NewEventInterrupt(nil))
+	})
 
-	screen.SetStyle(tcell.StyleDefault.
-		Background(tcell.ColorBlack).
-		Foreground(tcell.ColorWhite))
+	screen.SetStyle(ui.textStyle())
 	screen.Clear()
 
 	return ui, nil
 }
 
+// structuralLanguage picks the syntax-tree parser for a detected language,
+// falling back to the file extension for files chroma has no lexer for,
+// such as .tfvars.
+func structuralLanguage(language, path string) string {
+	if lang := syntax.LanguageForLexer(language); lang != "" {
+		return lang
+	}
+	return syntax.LanguageFor(path)
+}
+
+// Language returns the name of the language being highlighted, or "" for
+// plain text.
+func (ui *UI) Language() string {
+	return ui.language
+}
+
+// SetLanguage highlights the buffer as name, which may be any chroma
+// language name or alias, until it is changed again. An empty name goes
+// back to detecting the language. It reports false for unknown names.
+func (ui *UI) SetLanguage(name string) bool {
+	if name == "" {
+		ui.languageSet = false
+		ui.DetectLanguage()
+		return true
+	}
+	language := highlight.LanguageName(name)
+	if language == "" {
+		return false
+	}
+	ui.bg.SetLanguage(language, syntax.LanguageForLexer(language))
+	ui.language = language
+	ui.languageSet = true
+	ui.applyWrapDefault()
+	return true
+}
+
+// DetectLanguage re-detects the language from the buffer's path and text,
+// e.g. after the file is named. A language chosen with SetLanguage is kept.
+func (ui *UI) DetectLanguage() {
+	if ui.languageSet {
+		return
+	}
+	language := highlight.DetectLanguage(ui.buffer.FilePath, ui.buffer.Lines, ui.config.Editor.Languages)
+	if language == ui.language {
+		return
+	}
+	ui.bg.SetLanguage(language, structuralLanguage(language, ui.buffer.FilePath))
+	ui.language = language
+	ui.applyWrapDefault()
+}
+
+// SetTheme switches every colour, including syntax highlighting, to theme.
+func (ui *UI) SetTheme(theme themes.Theme) {
+	ui.theme = theme.WithDepth(ui.depth)
+	ui.highlighter.SetTheme(ui.theme, ui.config.Theme.SyntaxStyle)
+	ui.screen.SetStyle(ui.textStyle())
+}
+
+func (ui *UI) textStyle() tcell.Style {
+	return tcell.StyleDefault.
+		Background(ui.theme.Background).
+		Foreground(ui.theme.Foreground)
+}
+
+// gutterWidth is the number of columns taken by the sign column, the line
+// numbers, which grow with the line count and can be switched off, and the
+// fold markers.
+func (ui *UI) gutterWidth() int {
+	return ui.signWidth() + ui.numberWidth() + ui.foldWidth()
+}
+
+func (ui *UI) numberWidth() int {
+	if ui.config.Editor.ShowLineNums == config.LineNumbersOff {
+		return 0
+	}
+	return max(len(strconv.Itoa(len(ui.buffer.Lines))), minNumberWidth) + 1
+}
+
+func (ui *UI) gutterStyle() tcell.Style {
+	return tcell.StyleDefault.
+		Background(ui.theme.Background).
+		Foreground(ui.theme.LineNumFG)
+}
+
+// barStyle is used for the status bar and prompt popups.
+func (ui *UI) barStyle() tcell.Style {
+	if ui.depth == themes.DepthMono {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault.
+		Background(ui.theme.StatusBG).
+		Foreground(ui.theme.StatusFG)
+}
+
+func (ui *UI) selectionStyle() tcell.Style {
+	if ui.depth == themes.DepthMono {
+		return tcell.StyleDefault.Reverse(true)
+	}
+	return tcell.StyleDefault.
+		Background(ui.theme.SelectionBG).
+		Foreground(ui.theme.SelectionFG)
+}
+
 func (ui *UI) Close() {
+	ui.bg.Stop()
 	ui.screen.Fini()
 }
 
 func (ui *UI) Draw() {
 	ui.screen.Clear()
 	ui.width, ui.height = ui.screen.Size()
+	ui.bg.Submit(ui.buffer.Lines)
+	ui.syncFolds()
 
-	// Adjust vertical offset to keep cursor visible
+	// Adjust the view to keep the cursor visible
 	contentHeight := ui.height - 2 // Reserve space for status bars
-	if ui.buffer.CursorY < ui.offsetY {
-		ui.offsetY = ui.buffer.CursorY
+	col := buffer.ColumnAt(ui.buffer.GetCurrentLine(), ui.buffer.CursorX, ui.tabSize())
+	if ui.buffer.Block != nil {
+		col = ui.buffer.Block.CursorCol
 	}
-	if ui.buffer.CursorY >= ui.offsetY+contentHeight {
-		ui.offsetY = ui.buffer.CursorY - contentHeight + 1
+	if !ui.freeScroll {
+		ui.scrollToCursor(contentHeight)
+		ui.scrollToColumn(col)
 	}
 
-	// Draw lines
-	for i := 0; i < contentHeight; i++ {
-		lineNum := ui.offsetY + i
-		if lineNum >= len(ui.buffer.Lines) {
-			break
-		}
+	tokens, tree := ui.bg.Tokens(), ui.bg.Tree()
+	ui.match = ui.findMatch(tokens)
 
-		ui.drawLine(i, lineNum)
+	// Draw lines, skipping those inside closed folds
+	firstRow := ui.offsetRow
+	for screenY, lineNum := 0, ui.offsetY; screenY < contentHeight && lineNum < len(ui.buffer.Lines); lineNum = ui.folds.Next(lineNum) {
+		screenY += ui.drawLine(screenY, lineNum, firstRow, contentHeight-screenY, tokens, tree)
+		firstRow = 0
 	}
 
+	ui.drawExtraCursors(contentHeight)
+
 	// Draw status bar
 	ui.drawStatusBar()
 
@@ -83,56 +313,208 @@ func (ui *UI) Draw() {
 	ui.drawHelpBar()
 
 	// Position cursor
-	screenY := ui.buffer.CursorY - ui.offsetY
-	if screenY >= 0 && screenY < contentHeight {
-		ui.screen.ShowCursor(ui.buffer.CursorX+4, screenY) // +4 for line numbers
+	if x, y, ok := ui.cellAt(ui.buffer.CursorY, col, contentHeight); ok {
+		ui.screen.ShowCursor(x, y)
+	} else {
+		ui.screen.HideCursor()
 	}
 
 	ui.screen.Show()
 }
 
-func (ui *UI) drawLine(screenY, lineNum int) {
-	lineNumStr := fmt.Sprintf("%3d ", lineNum+1)
-	style := tcell.StyleDefault.
-		Background(tcell.ColorBlack).
-		Foreground(tcell.ColorGray)
-
-	for i, r := range lineNumStr {
-		ui.screen.SetContent(i, screenY, r, nil, style)
+// drawLine draws the rows of a buffer line from firstRow on, at most
+// maxRows of them, and returns how many it drew. It uses the worker's
+// latest results; a line the worker has not seen yet is drawn plain.
+func (ui *UI) drawLine(screenY, lineNum, firstRow, maxRows int, tokens highlight.Tokens, tree *syntax.Tree) int {
+	rows := ui.rows(lineNum)
+	lastRow := min(len(rows), firstRow+maxRows)
+	gutter := ui.gutterWidth()
+
+	// Only the first row of a wrapped line gets a sign, a number and a
+	// fold marker
+	style := ui.gutterStyle()
+	signs, numbers := ui.signWidth(), ui.numberWidth()
+	folds := ui.foldWidth()
+	for r := firstRow; r < lastRow && gutter > 0; r++ {
+		y := screenY + r - firstRow
+		for i := 0; i < gutter; i++ {
+			ui.screen.SetContent(i, y, ' ', nil, style)
+		}
+		if r > 0 {
+			continue
+		}
+		if sign, ok := ui.SignAt(lineNum); ok {
+			signStyle := ui.highlighter.CategoryStyle(sign.Category).Background(ui.theme.Background)
+			ui.screen.SetContent(0, y, sign.Glyph, nil, signStyle)
+		}
+		if numbers > 0 {
+			ui.drawText(signs, y, numbers, ui.lineLabel(lineNum, numbers), style)
+		}
+		if folds > 0 {
+			ui.screen.SetContent(signs+numbers, y, ui.foldMarker(lineNum), nil, style)
+		}
 	}
 
 	line := ui.buffer.Lines[lineNum]
-	styledRunes, err := ui.highlighter.HighlightLine(line)
-	if err != nil || len(styledRunes) == 0 {
+	styledRunes := ui.highlighter.StyleLine(tokens, lineNum, line)
+	if len(styledRunes) == 0 {
 		// Fallback to plain text
-		style := tcell.StyleDefault.
-			Background(tcell.ColorBlack).
-			Foreground(tcell.ColorWhite)
-		for i, r := range line {
-			if 4+i >= ui.width {
-				break
+		styledRunes = make([]highlight.StyledRune, 0, len(line))
+		for _, r := range line {
+			styledRunes = append(styledRunes, highlight.StyledRune{Rune: r, Style: ui.textStyle()})
+		}
+	}
+
+	selectedStyle := ui.selectionStyle()
+
+	// Block selections are drawn underlined so they stand apart from
+	// ordinary stream selections.
+	blockStyle := selectedStyle.Underline(true)
+
+	marks := ui.lineMarks(lineNum)
+	ui.drawGuides(screenY, lastRow-firstRow, firstRow == 0, marks)
+
+	// The structural parser classifies some ranges better than the lexer
+	var overlay []syntax.LineToken
+	if tree != nil {
+		overlay = tree.LineTokens(lineNum)
+	}
+
+	// Track the byte offset alongside the display column so the selection,
+	// which is stored in bytes, lines up with multi-byte and wide runes.
+	offset := 0
+	col := 0
+	// r is the row being drawn and origin the screen x of its column 0
+	r := 0
+	origin := gutter - ui.offsetX
+	tabSize := ui.tabSize()
+	for _, sr := range styledRunes {
+		for r < len(rows)-1 && offset >= rows[r+1].start {
+			r++
+			origin = gutter + rows[r].indent - col
+		}
+		if r >= lastRow {
+			break
+		}
+		x := origin + col
+		w := buffer.CellWidth(sr.Rune, col, tabSize)
+		if r < firstRow || x < gutter || x >= ui.width {
+			offset += utf8.RuneLen(sr.Rune)
+			col += w
+			continue
+		}
+		style := sr.Style
+		for len(overlay) > 0 && overlay[0].EndX <= offset {
+			overlay = overlay[1:]
+		}
+		if len(overlay) > 0 && overlay[0].StartX <= offset {
+			style = ui.highlighter.CategoryStyle(overlay[0].Category)
+		}
+		if ui.isMatched(offset, lineNum) {
+			style = matchStyle(style)
+		}
+		if ui.buffer.InBlock(col, lineNum) {
+			style = blockStyle
+		} else if ui.buffer.IsSelected(offset, lineNum) {
+			style = selectedStyle
+		}
+		ch := sr.Rune
+		if mark, ok := ui.markRune(ch, offset, col, marks); ok {
+			ch, style = mark, ui.whitespaceStyle(style)
+		} else if ch == '\t' {
+			ch = ' '
+		}
+		ui.screen.SetContent(x, screenY+r-firstRow, ch, nil, style)
+		if sr.Rune == '\t' {
+			// The rest of the tab is blank, in the same style so selections
+			// cover it
+			for i := 1; i < w && x+i < ui.width; i++ {
+				ui.screen.SetContent(x+i, screenY+r-firstRow, ' ', nil, style)
 			}
-			ui.screen.SetContent(4+i, screenY, r, nil, style)
+		}
+		offset += utf8.RuneLen(sr.Rune)
+		col += w
+	}
+
+	// The line's end is on its last row, which may be scrolled off
+	for r < len(rows)-1 && offset >= rows[r+1].start {
+		r++
+		origin = gutter + rows[r].indent - col
+	}
+	if r != len(rows)-1 || r < firstRow || r >= lastRow {
+		return lastRow - firstRow
+	}
+	endY := screenY + r - firstRow
+
+	// Show the selected line break as a single highlighted cell
+	if x := origin + col; x >= gutter && x < ui.width && ui.buffer.IsSelected(len(line), lineNum) {
+		ui.screen.SetContent(x, endY, ' ', nil, selectedStyle)
+	}
+
+	if ui.buffer.Block != nil {
+		ui.drawBlockTail(endY, lineNum, col, origin, blockStyle)
+	}
+
+	if r, ok := ui.folds.Closed(lineNum); ok {
+		summaryStyle := ui.highlighter.CategoryStyle("comment").Reverse(true)
+		ui.drawText(max(origin+col+1, gutter), endY, max(ui.width-origin-col-1, 0), foldSummary(r), summaryStyle)
+	}
+	return lastRow - firstRow
+}
+
+// SyntaxTree returns the structural parse of the buffer's current text,
+// waiting for the worker to catch up, or nil if there is none.
+func (ui *UI) SyntaxTree() *syntax.Tree {
+	ui.bg.Submit(ui.buffer.Lines)
+	ui.bg.Wait()
+	return ui.bg.Tree()
+}
+
+// SyntaxError returns the error from the last structural parse.
+func (ui *UI) SyntaxError() error {
+	return ui.bg.TreeError()
+}
+
+// drawBlockTail fills the part of a block selection that lies past the end
+// of a short line, and marks the insertion column of a zero-width block.
+// origin is the screen x of the row's column 0.
+func (ui *UI) drawBlockTail(screenY, lineNum, lineWidth, origin int, style tcell.Style) {
+	startCol, startY, endCol, endY := ui.buffer.BlockBounds()
+	if lineNum < startY || lineNum > endY {
+		return
+	}
+
+	if startCol == endCol {
+		if x := origin + startCol; x >= ui.gutterWidth() && x < ui.width {
+			mainc, combc, cellStyle, _ := ui.screen.GetContent(x, screenY)
+			ui.screen.SetContent(x, screenY, mainc, combc, cellStyle.Underline(true))
 		}
 		return
 	}
 
-	for i, sr := range styledRunes {
-		if 4+i >= ui.width {
-			break
+	for c := max(lineWidth, startCol, ui.gutterWidth()-origin); c < endCol && origin+c < ui.width; c++ {
+		ui.screen.SetContent(origin+c, screenY, ' ', nil, style)
+	}
+}
+
+// drawExtraCursors shows secondary cursors as reversed cells; the terminal
+// cursor only marks the primary one.
+func (ui *UI) drawExtraCursors(contentHeight int) {
+	cursors := ui.buffer.Cursors()
+	for _, c := range cursors[1:] {
+		line := ui.buffer.Lines[c.CursorY]
+		x, screenY, ok := ui.cellAt(c.CursorY, buffer.ColumnAt(line, c.CursorX, ui.tabSize()), contentHeight)
+		if !ok {
+			continue
 		}
-		style := tcell.StyleDefault.
-			Background(tcell.ColorBlack).
-			Foreground(sr.Color)
-		ui.screen.SetContent(4+i, screenY, sr.Rune, nil, style)
+		mainc, combc, style, _ := ui.screen.GetContent(x, screenY)
+		ui.screen.SetContent(x, screenY, mainc, combc, style.Reverse(true))
 	}
 }
 
 func (ui *UI) drawStatusBar() {
 	y := ui.height - 2
-	style := tcell.StyleDefault.
-		Background(tcell.ColorBlue).
-		Foreground(tcell.ColorWhite)
+	style := ui.barStyle()
 
 	// Clear status bar
 	for x := 0; x < ui.width; x++ {
@@ -149,11 +531,27 @@ func (ui *UI) drawStatusBar() {
 		fileName = "[No Name]"
 	}
 
-	status := fmt.Sprintf(" %s%s | Line %d/%d, Col %d",
-		modFlag, fileName, ui.buffer.CursorY+1, len(ui.buffer.Lines), ui.buffer.CursorX+1)
+	status := fmt.Sprintf(" %s%s | ", modFlag, fileName)
+	if ui.language != "" {
+		status += ui.language + " | "
+	}
+	status += ui.buffer.IndentStyle().String() + " | "
+	status += fmt.Sprintf("Line %d/%d, Col %d",
+		ui.buffer.CursorY+1, len(ui.buffer.Lines), ui.buffer.CursorX+1)
 
-	if ui.statusMsg != "" {
-		status += " | " + ui.statusMsg
+	if ui.buffer.HasMultipleCursors() {
+		status += fmt.Sprintf(" | %d cursors", ui.buffer.CursorCount())
+	}
+
+	msg := ui.statusMsg
+	if sign, ok := ui.SignAt(ui.buffer.CursorY); ok && msg == "" {
+		msg = sign.Message
+	}
+	if msg == "" {
+		msg = ui.matchHint()
+	}
+	if msg != "" {
+		status += " | " + msg
 	}
 
 	for i, r := range status {
@@ -166,22 +564,89 @@ func (ui *UI) drawStatusBar() {
 
 func (ui *UI) drawHelpBar() {
 	y := ui.height - 1
-	style := tcell.StyleDefault.
-		Background(tcell.ColorBlack).
-		Foreground(tcell.ColorWhite)
-
-	help := " ^S Save | ^Q Quit | ^K Del | ^Z Undo | ^T Top | ^B Bottom | ^W Select | ^A AI | ^E Emoji | ^F Format"
+	style := ui.textStyle()
 
 	for x := 0; x < ui.width; x++ {
 		ui.screen.SetContent(x, y, ' ', nil, style)
 	}
 
-	for i, r := range help {
-		if i >= ui.width {
-			break
+	x := 1
+	for i := range ui.helpItems {
+		item := &ui.helpItems[i]
+		if i > 0 {
+			ui.drawText(x, y, ui.width-x, " | ", style)
+			x += 3
 		}
-		ui.screen.SetContent(i, y, r, nil, style)
+		item.start = x
+		ui.drawText(x, y, ui.width-x, item.label, style)
+		x += len(item.label)
+		item.end = x
+	}
+}
+
+// HelpKeyAt returns the key of the help bar entry drawn at column x.
+func (ui *UI) HelpKeyAt(x int) (tcell.Key, bool) {
+	for _, item := range ui.helpItems {
+		if x >= item.start && x < item.end {
+			return item.key, true
+		}
+	}
+	return 0, false
+}
+
+// IsHelpBarRow reports whether screen row y is the help bar.
+func (ui *UI) IsHelpBarRow(y int) bool {
+	return y == ui.height-1
+}
+
+// ScreenToBuffer maps a screen cell in the text area to a buffer position,
+// skipping the gutter and snapping to the start of wide runes. Rows below
+// the text area map one line past the last visible line so dragging there
+// scrolls the view.
+func (ui *UI) ScreenToBuffer(x, y int) (int, int, bool) {
+	contentHeight := ui.height - 2
+	if y < 0 || y > contentHeight {
+		return 0, 0, false
+	}
+
+	lineNum, r := ui.moveRows(ui.offsetY, ui.offsetRow, y)
+	line := ui.buffer.Lines[lineNum]
+	rows := ui.rows(lineNum)
+	col := buffer.ColumnAt(line, rows[r].start, ui.tabSize()) + ui.offsetX + max(x-ui.gutterWidth()-rows[r].indent, 0)
+	offset := buffer.OffsetAtColumnFloor(line, col, ui.tabSize())
+	if r < len(rows)-1 && offset >= rows[r].end {
+		offset = lastRuneStart(line, rows[r].start, rows[r].end)
 	}
+	return offset, lineNum, true
+}
+
+// Scroll moves the view by delta screen rows without moving the cursor.
+func (ui *UI) Scroll(delta int) {
+	contentHeight := ui.height - 2
+	y, r := ui.moveRows(ui.offsetY, ui.offsetRow, delta)
+
+	// Stop once the last row reaches the bottom of the screen
+	lastY := ui.VisibleLine(len(ui.buffer.Lines) - 1)
+	maxY, maxRow := ui.moveRows(lastY, len(ui.rows(lastY))-1, -(contentHeight - 1))
+	if before(maxY, maxRow, y, r) {
+		y, r = maxY, maxRow
+	}
+	ui.offsetY, ui.offsetRow = y, r
+	ui.freeScroll = true
+}
+
+// FollowCursor makes the view track the cursor again after free scrolling.
+func (ui *UI) FollowCursor() {
+	ui.freeScroll = false
+}
+
+// Tty returns a writer for sending raw escape sequences to the terminal,
+// such as OSC 52 clipboard requests.
+func (ui *UI) Tty() io.Writer {
+	if tty, ok := ui.screen.Tty(); ok {
+		return tty
+	}
+	return os.Stdout
 }
 
 func (ui *UI) PollEvent() tcell.Event {
@@ -206,9 +671,7 @@ func (ui *UI) ShowPrompt(prompt string) (string, bool) {
 	}
 	startX := midX - boxWidth/2
 
-	style := tcell.StyleDefault.
-		Background(tcell.ColorBlue).
-		Foreground(tcell.ColorWhite)
+	style := ui.barStyle()
 
 	// Draw prompt text
 	for i, r := range prompt {
@@ -287,9 +750,7 @@ func (ui *UI) ShowAIPrompt(prompt string) (string, string, bool) {
 	}
 	startX := midX - boxWidth/2
 
-	style := tcell.StyleDefault.
-		Background(tcell.ColorBlue).
-		Foreground(tcell.ColorWhite)
+	style := ui.barStyle()
 
 	mode := "insert"
 	modeText := "[INSERT]"
@@ -407,9 +868,7 @@ func (ui *UI) ShowEmojiPicker() (string, bool) {
 
 	for {
 		// Clear with black background
-		defaultStyle := tcell.StyleDefault.
-			Background(tcell.ColorBlack).
-			Foreground(tcell.ColorWhite)
+		defaultStyle := ui.textStyle()
 		for y := 0; y < ui.height; y++ {
 			for x := 0; x < ui.width; x++ {
 				ui.screen.SetContent(x, y, ' ', nil, defaultStyle)
@@ -430,13 +889,9 @@ func (ui *UI) ShowEmojiPicker() (string, bool) {
 		startX := midX - boxWidth/2
 		startY := midY - boxHeight/2
 
-		style := tcell.StyleDefault.
-			Background(tcell.ColorBlack).
-			Foreground(tcell.ColorWhite)
+		style := ui.textStyle()
 
-		selectedStyle := tcell.StyleDefault.
-			Background(tcell.ColorWhite).
-			Foreground(tcell.ColorBlack)
+		selectedStyle := ui.selectionStyle()
 
 		// Title
 		title := " Emoji Picker (↑↓ navigate, Enter select, Esc cancel) "
@@ -512,3 +967,142 @@ func (ui *UI) ShowEmojiPicker() (string, bool) {
 		}
 	}
 }
+
+// ShowKeyPrompt shows msg in the status bar and waits for a single rune.
+// It returns false if the user presses Esc or a non-character key.
+func (ui *UI) ShowKeyPrompt(msg string) (rune, bool) {
+	prev := ui.statusMsg
+	ui.statusMsg = msg
+	ui.Draw()
+	ui.statusMsg = prev
+
+	for {
+		ev := ui.screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			if ev.Key() == tcell.KeyRune {
+				return ev.Rune(), true
+			}
+			return 0, false
+		case *tcell.EventResize:
+			ui.Draw()
+		}
+	}
+}
+
+// ShowInfo shows lines in a box over the editor until a key is pressed.
+func (ui *UI) ShowInfo(title string, lines []string) {
+	for {
+		style := ui.barStyle()
+		boxWidth := min(70, ui.width-4)
+		boxHeight := min(len(lines)+2, ui.height-4)
+		startX := ui.width/2 - boxWidth/2
+		startY := ui.height/2 - boxHeight/2
+
+		for y := startY; y < startY+boxHeight; y++ {
+			for x := startX; x < startX+boxWidth; x++ {
+				ui.screen.SetContent(x, y, ' ', nil, style)
+			}
+		}
+		ui.drawText(startX+1, startY, boxWidth-2, title, style.Bold(true))
+		for i := 0; i < boxHeight-2 && i < len(lines); i++ {
+			ui.drawText(startX+1, startY+1+i, boxWidth-2, lines[i], style)
+		}
+		ui.screen.HideCursor()
+		ui.screen.Show()
+
+		switch ui.screen.PollEvent().(type) {
+		case *tcell.EventKey:
+			return
+		case *tcell.EventResize:
+			ui.Draw()
+		}
+	}
+}
+
+// ShowListPicker shows a scrollable list of one-line items and returns the
+// index of the chosen one.
+func (ui *UI) ShowListPicker(title string, items []string) (int, bool) {
+	selected := 0
+	offset := 0
+
+	for {
+		defaultStyle := ui.textStyle()
+		for y := 0; y < ui.height; y++ {
+			for x := 0; x < ui.width; x++ {
+				ui.screen.SetContent(x, y, ' ', nil, defaultStyle)
+			}
+		}
+
+		boxWidth := 70
+		boxHeight := 15
+		if boxWidth > ui.width-4 {
+			boxWidth = ui.width - 4
+		}
+		if boxHeight > ui.height-4 {
+			boxHeight = ui.height - 4
+		}
+		startX := ui.width/2 - boxWidth/2
+		startY := ui.height/2 - boxHeight/2
+
+		selectedStyle := ui.selectionStyle()
+
+		ui.drawText(startX, startY, boxWidth, " "+title+" ", defaultStyle)
+
+		visibleRows := boxHeight - 2
+		if selected < offset {
+			offset = selected
+		}
+		if selected >= offset+visibleRows {
+			offset = selected - visibleRows + 1
+		}
+
+		for row := 0; row < visibleRows && offset+row < len(items); row++ {
+			idx := offset + row
+			style := defaultStyle
+			if idx == selected {
+				style = selectedStyle
+			}
+			y := startY + 1 + row
+			for x := startX; x < startX+boxWidth; x++ {
+				ui.screen.SetContent(x, y, ' ', nil, style)
+			}
+			ui.drawText(startX+1, y, boxWidth-2, fmt.Sprintf("%2d  %s", idx+1, items[idx]), style)
+		}
+
+		ui.screen.HideCursor()
+		ui.screen.Show()
+
+		ev := ui.screen.PollEvent()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			switch ev.Key() {
+			case tcell.KeyEnter:
+				return selected, true
+			case tcell.KeyEscape:
+				return 0, false
+			case tcell.KeyUp:
+				if selected > 0 {
+					selected--
+				}
+			case tcell.KeyDown:
+				if selected < len(items)-1 {
+					selected++
+				}
+			}
+		}
+	}
+}
+
+// drawText writes s at (x, y), clipped to width cells.
+func (ui *UI) drawText(x, y, width int, s string, style tcell.Style) {
+	col := 0
+	for _, r := range s {
+		w := buffer.RuneWidth(r)
+		if col+w > width {
+			break
+		}
+		ui.screen.SetContent(x+col, y, r, nil, style)
+		col += w
+	}
+}
@@ -0,0 +1,429 @@
+package ui
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/config"
+	"github.com/justynroberts/finpup/internal/fold"
+)
+
+const goSnippet = `// Package demo is a sample file.
+package demo
+
+/* block comment
+   over two lines */
+func add(a, b int) int {
+	return a + b // sum
+}
+`
+
+func newTestUI(tb testing.TB, path, text string) *UI {
+	tb.Helper()
+	buf, _ := buffer.New("")
+	buf.FilePath = path
+	buf.InsertText(text)
+	buf.CursorX, buf.CursorY = 0, 0
+
+	cfg := config.DefaultConfig
+	ui, err := NewWithScreen(tcell.NewSimulationScreen(""), buf, &cfg)
+	if err != nil {
+		tb.Fatalf("newUI failed: %v", err)
+	}
+	tb.Cleanup(ui.Close)
+	ui.screen.SetSize(100, 40)
+	return ui
+}
+
+func cellStyle(ui *UI, x, y int) tcell.Style {
+	_, _, style, _ := ui.screen.GetContent(x, y)
+	return style
+}
+
+func cursorPos(ui *UI) (int, int) {
+	x, y, _ := ui.screen.(tcell.SimulationScreen).GetCursor()
+	return x, y
+}
+
+func TestDrawHighlightsOnceWorkerCatchesUp(t *testing.T) {
+	ui := newTestUI(t, "demo.go", goSnippet)
+
+	ui.Draw()
+	ui.bg.Wait()
+	ui.Draw()
+
+	// "package" on line 2 is a keyword, "demo" is not
+	keyword := cellStyle(ui, ui.gutterWidth(), 1)
+	name := cellStyle(ui, ui.gutterWidth()+len("package "), 1)
+	if keyword == name {
+		t.Error("Expected the keyword to be styled differently from the package name")
+	}
+
+	// The second line of the block comment is styled as a comment
+	comment := cellStyle(ui, ui.gutterWidth()+3, 4)
+	if comment != cellStyle(ui, ui.gutterWidth(), 0) {
+		t.Error("Expected the continued block comment to match the line comment style")
+	}
+}
+
+func TestDrawPlainForUnhighlightedLines(t *testing.T) {
+	ui := newTestUI(t, "demo.go", goSnippet)
+	ui.Draw()
+	ui.bg.Wait()
+
+	// Draw with a snapshot taken before the edit: the edited line is plain
+	tokens := ui.bg.Tokens()
+	ui.buffer.CursorY = 1
+	ui.buffer.InsertRune('x')
+	ui.drawLine(1, 1, 0, 1, tokens, nil)
+
+	if style := cellStyle(ui, ui.gutterWidth()+1, 1); style != ui.textStyle() {
+		t.Errorf("Expected plain text for a line the worker has not seen, got %v", style)
+	}
+}
+
+func TestSetLanguageOverridesDetection(t *testing.T) {
+	ui := newTestUI(t, "snippet.txt", goSnippet)
+	redraw := func() {
+		ui.Draw()
+		ui.bg.Wait()
+		ui.Draw()
+	}
+	keywordStyled := func() bool {
+		return cellStyle(ui, ui.gutterWidth(), 1) != cellStyle(ui, ui.gutterWidth()+len("package "), 1)
+	}
+
+	redraw()
+	if keywordStyled() || ui.SyntaxTree() != nil {
+		t.Fatalf("Expected a .txt file to be plain, detected %q", ui.Language())
+	}
+
+	if !ui.SetLanguage("golang") {
+		t.Fatal("Expected the golang alias to be accepted")
+	}
+	redraw()
+	if ui.Language() != "Go" || !keywordStyled() {
+		t.Errorf("Expected Go highlighting, got language %q", ui.Language())
+	}
+	if ui.SyntaxTree() == nil {
+		t.Error("Expected a syntax tree once the language is Go")
+	}
+
+	// A chosen language survives re-detection until reset
+	ui.DetectLanguage()
+	if ui.Language() != "Go" {
+		t.Errorf("Expected re-detection to keep Go, got %q", ui.Language())
+	}
+	ui.SetLanguage("")
+	if ui.Language() == "Go" {
+		t.Error("Expected resetting to detect from the file name again")
+	}
+
+	if ui.SetLanguage("no-such-language") {
+		t.Error("Expected an unknown language to be rejected")
+	}
+}
+
+func TestGutterFollowsLineCount(t *testing.T) {
+	ui := newTestUI(t, "big.txt", strings.Repeat("x\n", 12345))
+	if w := ui.gutterWidth(); w != 6 {
+		t.Errorf("Expected a 6-column gutter for 12346 lines, got %d", w)
+	}
+
+	ui.buffer.CursorY = 12344
+	ui.Draw()
+	x, _ := cursorPos(ui)
+	if x != 6 {
+		t.Errorf("Expected the cursor just after the gutter, got column %d", x)
+	}
+
+	ui.config.Editor.ShowLineNums = config.LineNumbersOff
+	ui.Draw()
+	if x, _ := cursorPos(ui); x != 0 {
+		t.Errorf("Expected no gutter with line numbers off, got column %d", x)
+	}
+}
+
+func TestHorizontalScrollKeepsCursorVisible(t *testing.T) {
+	line := strings.Repeat("abcdefghij", 30)
+	ui := newTestUI(t, "long.go", line)
+	ui.buffer.CursorX = 150
+	ui.Draw()
+
+	// 96 text columns with a margin of 5 after the cursor
+	if ui.offsetX != 150-96+5+1 {
+		t.Errorf("Expected offset %d, got %d", 150-96+5+1, ui.offsetX)
+	}
+	if x, _ := cursorPos(ui); x != ui.width-6 {
+		t.Errorf("Expected the cursor 5 columns from the edge, got %d", x)
+	}
+	if bx, _, _ := ui.ScreenToBuffer(ui.gutterWidth(), 0); bx != ui.offsetX {
+		t.Errorf("Expected the first text column to map to offset %d, got %d", ui.offsetX, bx)
+	}
+
+	ui.buffer.CursorX = 2
+	ui.Draw()
+	if ui.offsetX != 0 {
+		t.Errorf("Expected to scroll back to the start, got offset %d", ui.offsetX)
+	}
+}
+
+func TestTabsReachTabStops(t *testing.T) {
+	ui := newTestUI(t, "Makefile", "all:\n\tgo build\n")
+	ui.buffer.TabSize = 8
+	ui.buffer.CursorX, ui.buffer.CursorY = 1, 1
+	ui.Draw()
+
+	if x, _ := cursorPos(ui); x != ui.gutterWidth()+8 {
+		t.Errorf("Expected the cursor after an 8-column tab, got column %d", x-ui.gutterWidth())
+	}
+	if bx, by, _ := ui.ScreenToBuffer(ui.gutterWidth()+5, 1); bx != 0 || by != 1 {
+		t.Errorf("Expected a click inside the tab to land on it, got (%d, %d)", bx, by)
+	}
+}
+
+func TestRelativeLineNumbers(t *testing.T) {
+	ui := newTestUI(t, "demo.go", goSnippet)
+	ui.buffer.CursorY = 3
+
+	label := func(line int) string { return ui.lineLabel(line, ui.numberWidth()) }
+	ui.config.Editor.ShowLineNums = config.LineNumbersRelative
+	if got := label(1) + label(3) + label(5); got != "  2   0   2 " {
+		t.Errorf("Expected distances from the cursor, got %q", got)
+	}
+	ui.config.Editor.ShowLineNums = config.LineNumbersHybrid
+	if got := label(1) + label(3); got != "  2   4 " {
+		t.Errorf("Expected the cursor line numbered absolutely, got %q", got)
+	}
+}
+
+func TestSigns(t *testing.T) {
+	ui := newTestUI(t, "demo.go", goSnippet)
+	ui.currentRegions()
+	ui.Draw()
+	base := ui.gutterWidth()
+	if base != 4+foldColumnWidth {
+		t.Fatalf("Expected no sign column without signs, got gutter %d", base)
+	}
+
+	ui.SetSigns("search", []Sign{{Line: 1, Glyph: '>', Category: "string", Priority: 1, Message: "match"}})
+	ui.SetSigns("lint", []Sign{{Line: 1, Glyph: 'E', Category: "error", Priority: 10, Message: "bad package"}})
+	ui.buffer.CursorY = 1
+	ui.Draw()
+
+	if ui.gutterWidth() != base+signColumnWidth {
+		t.Errorf("Expected the sign column to widen the gutter, got %d", ui.gutterWidth())
+	}
+	if mainc, _, _, _ := ui.screen.GetContent(0, 1); mainc != 'E' {
+		t.Errorf("Expected the higher-priority sign, got %q", mainc)
+	}
+	if sign, ok := ui.SignAtScreen(0, 1); !ok || sign.Message != "bad package" {
+		t.Errorf("Expected to find the lint sign under the mouse, got %+v", sign)
+	}
+
+	ui.ClearSigns("lint")
+	if sign, _ := ui.SignAt(1); sign.Glyph != '>' {
+		t.Errorf("Expected the search sign once lint is cleared, got %q", sign.Glyph)
+	}
+	ui.ClearSigns("search")
+	if ui.gutterWidth() != base {
+		t.Errorf("Expected the sign column to hide again, got gutter %d", ui.gutterWidth())
+	}
+}
+
+func TestSignsFollowEdits(t *testing.T) {
+	ui := newTestUI(t, "demo.go", goSnippet)
+	ui.SetSigns("lint", []Sign{{Line: 1, Glyph: 'E'}, {Line: 3, Glyph: 'W'}, {Line: 4, Glyph: 'I'}})
+	ui.buffer.CursorY = 1
+	ui.Draw()
+
+	// A line opened below the cursor pushes the later signs down
+	ui.buffer.Lines = append(ui.buffer.Lines[:2], append([]string{""}, ui.buffer.Lines[2:]...)...)
+	ui.buffer.CursorY = 2
+	ui.Draw()
+	for line, glyph := range map[int]rune{1: 'E', 4: 'W', 5: 'I'} {
+		if sign, ok := ui.SignAt(line); !ok || sign.Glyph != glyph {
+			t.Errorf("Expected %q on line %d after inserting, got %q", glyph, line, sign.Glyph)
+		}
+	}
+
+	// Deleting the line after the cursor drops its sign and pulls the
+	// rest up
+	ui.buffer.CursorY = 3
+	ui.Draw()
+	ui.buffer.Lines = append(ui.buffer.Lines[:4], ui.buffer.Lines[5:]...)
+	ui.Draw()
+	if sign, ok := ui.SignAt(4); !ok || sign.Glyph != 'I' {
+		t.Errorf("Expected 'I' on line 4 after deleting, got %q", sign.Glyph)
+	}
+	if len(ui.signs["lint"]) != 2 {
+		t.Errorf("Expected the deleted line's sign dropped, got %+v", ui.signs["lint"])
+	}
+}
+
+func TestWhitespaceGuidesAndRulers(t *testing.T) {
+	ui := newTestUI(t, "notes.txt", "a\tb  \nc\u00a0d\n        x\n\n    y")
+	ui.config.Editor.ShowLineNums = config.LineNumbersOff
+	ui.config.Editor.IndentGuides = true
+	ui.config.Editor.Rulers = []int{20}
+	ui.currentRegions()
+	ui.Draw()
+
+	// x is a text column; the fold markers take the gutter
+	cell := func(x, y int) rune {
+		mainc, _, _, _ := ui.screen.GetContent(ui.gutterWidth()+x, y)
+		return mainc
+	}
+	glyphs := ui.theme.Glyphs
+	if cell(1, 0) != '\t' && cell(1, 0) != ' ' {
+		t.Errorf("Expected whitespace to be hidden by default, got %q", cell(1, 0))
+	}
+	if cell(0, 2) != glyphs.Guide || cell(4, 2) != glyphs.Guide || cell(8, 2) != 'x' {
+		t.Errorf("Expected guides at each level of indentation, got %q %q", cell(0, 2), cell(4, 2))
+	}
+	if cell(0, 3) != glyphs.Guide || cell(4, 3) == glyphs.Guide {
+		t.Error("Expected a blank line to continue the shallower neighbour's guides")
+	}
+	if cell(20, 0) != glyphs.Guide || cell(20, 4) != glyphs.Guide {
+		t.Error("Expected the ruler on every line")
+	}
+
+	ui.ToggleWhitespace()
+	ui.Draw()
+	// The tab reaches column 4
+	if cell(1, 0) != glyphs.Tab || cell(2, 0) != ' ' || cell(4, 0) != 'b' {
+		t.Errorf("Expected a tab glyph padded to the tab stop, got %q%q%q", cell(1, 0), cell(2, 0), cell(4, 0))
+	}
+	if cell(5, 0) != glyphs.Space || cell(6, 0) != glyphs.Space {
+		t.Errorf("Expected trailing space glyphs, got %q%q", cell(5, 0), cell(6, 0))
+	}
+	if cell(1, 1) != glyphs.NBSP {
+		t.Errorf("Expected a non-breaking space glyph, got %q", cell(1, 1))
+	}
+}
+
+func TestFolding(t *testing.T) {
+	ui := newTestUI(t, "demo.go", goSnippet)
+	// Regions: the block comment on lines 3-4 and the body of add on 5-6
+	if _, ok := ui.ToggleFold(5); !ok {
+		t.Fatal("Expected a region to fold at the function")
+	}
+	ui.Draw()
+
+	marker := ui.signWidth() + ui.numberWidth()
+	if mainc, _, _, _ := ui.screen.GetContent(marker, 5); mainc != foldClosedMarker {
+		t.Errorf("Expected a closed fold marker, got %q", mainc)
+	}
+	if mainc, _, _, _ := ui.screen.GetContent(marker, 3); mainc != foldOpenMarker {
+		t.Errorf("Expected an open fold marker on the comment, got %q", mainc)
+	}
+	row := func(y int) string {
+		var sb strings.Builder
+		for x := ui.gutterWidth(); x < ui.width; x++ {
+			mainc, _, _, _ := ui.screen.GetContent(x, y)
+			sb.WriteRune(mainc)
+		}
+		return strings.TrimRight(sb.String(), " ")
+	}
+	if got := row(5); !strings.HasSuffix(got, strings.TrimSpace(foldSummary(fold.Region{Start: 5, End: 6}))) {
+		t.Errorf("Expected the fold summary after the first line, got %q", got)
+	}
+	if got := row(6); got != "}" {
+		t.Errorf("Expected the closing brace right after the fold, got %q", got)
+	}
+
+	if next := ui.NextLine(5); next != 7 {
+		t.Errorf("Expected NextLine to skip the hidden line, got %d", next)
+	}
+	if x, y := ui.VisualMove(0, 7, -1); x != 0 || y != 5 {
+		t.Errorf("Expected moving up to land on the fold, got (%d, %d)", x, y)
+	}
+
+	// Moving the cursor into the fold opens it
+	ui.buffer.CursorY = 6
+	ui.Draw()
+	if ui.Folded(5) {
+		t.Error("Expected the fold hiding the cursor to open")
+	}
+
+	if n := ui.FoldToLevel(0); n != 2 {
+		t.Errorf("Expected both regions to fold, got %d", n)
+	}
+	ui.UnfoldAll()
+	if ui.Folded(3) || ui.Folded(5) {
+		t.Error("Expected every fold to open")
+	}
+}
+
+func TestMatchingBrackets(t *testing.T) {
+	text := "func f() {\n\ts := \"}\" // )\n" + strings.Repeat("\tg()\n", 50) + "}\n"
+	ui := newTestUI(t, "demo.go", text)
+	ui.Draw()
+	ui.bg.Wait()
+	ui.buffer.CursorX = len("func f() {")
+
+	// The brace before the cursor pairs with the last line, skipping the
+	// string and comment, and is out of sight
+	ui.Draw()
+	if m := ui.match; !m.ok || m.x != 9 || m.partnerY != 52 || m.partnerX != 0 {
+		t.Fatalf("Expected { to match line 53, got %+v", m)
+	}
+	if _, _, attrs := cellStyle(ui, ui.gutterWidth()+9, 0).Decompose(); attrs&tcell.AttrUnderline == 0 {
+		t.Error("Expected the bracket to be underlined")
+	}
+	if hint := ui.matchHint(); hint != "Matches line 53: }" {
+		t.Errorf("Expected a hint for the off-screen brace, got %q", hint)
+	}
+
+	// From the closer the search runs backwards
+	ui.buffer.CursorY, ui.buffer.CursorX = 2, 4
+	ui.Draw()
+	if m := ui.match; !m.ok || m.partnerY != 2 || m.partnerX != 2 || m.x != 3 {
+		t.Errorf("Expected ( and ) on line 3 to match, got %+v", m)
+	}
+
+	// Nothing next to the cursor
+	ui.buffer.CursorX = 0
+	ui.Draw()
+	if ui.match.ok {
+		t.Error("Expected no match away from brackets")
+	}
+}
+
+// BenchmarkKeystroke measures the work done on the input goroutine for one
+// keypress in a large file: the edit plus a full redraw.
+func BenchmarkKeystroke(b *testing.B) {
+	text := strings.Repeat(goSnippet, 5000) // 40k lines
+	ui := newTestUI(b, "big.go", text)
+	ui.Draw()
+	ui.bg.Wait()
+
+	ui.buffer.CursorY = len(ui.buffer.Lines) / 2
+	ui.buffer.CursorX = 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ui.buffer.InsertRune('x')
+		ui.Draw()
+	}
+}
+
+func TestSetEnvRestores(t *testing.T) {
+	t.Setenv("COLORTERM", "256color")
+	restore := setEnv("COLORTERM", "truecolor")
+	if got := os.Getenv("COLORTERM"); got != "truecolor" {
+		t.Fatalf("Expected truecolor while set, got %q", got)
+	}
+	restore()
+	if got := os.Getenv("COLORTERM"); got != "256color" {
+		t.Errorf("Expected 256color restored, got %q", got)
+	}
+
+	os.Unsetenv("COLORTERM")
+	setEnv("COLORTERM", "truecolor")()
+	if _, ok := os.LookupEnv("COLORTERM"); ok {
+		t.Error("Expected COLORTERM unset again")
+	}
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/pkg/themes"
+)
+
+// guideScan is how far a blank line looks up and down for the
+// indentation its guides continue.
+const guideScan = 100
+
+// lineMarks says where on a line whitespace is drawn visibly.
+type lineMarks struct {
+	// trailing is the offset where trailing whitespace starts
+	trailing int
+	// indent is the offset where the text after the indentation starts
+	indent int
+	// guides is the column up to which indentation guides are drawn
+	guides int
+}
+
+// ToggleWhitespace switches visible whitespace on or off and reports the
+// new state.
+func (ui *UI) ToggleWhitespace() bool {
+	ui.config.Editor.ShowWhitespace = !ui.config.Editor.ShowWhitespace
+	return ui.config.Editor.ShowWhitespace
+}
+
+// tabSize is the buffer's tab stop and indentation width.
+func (ui *UI) tabSize() int {
+	return ui.buffer.TabStop()
+}
+
+func (ui *UI) whitespaceStyle(style tcell.Style) tcell.Style {
+	if ui.depth == themes.DepthMono {
+		return style.Dim(true)
+	}
+	return style.Foreground(ui.theme.WhitespaceFG)
+}
+
+func (ui *UI) lineMarks(lineNum int) lineMarks {
+	line := ui.buffer.Lines[lineNum]
+	code := strings.TrimLeft(line, " \t")
+	m := lineMarks{
+		trailing: len(strings.TrimRight(line, " \t")),
+		indent:   len(line) - len(code),
+	}
+	if ui.config.Editor.IndentGuides {
+		m.guides = ui.guideIndent(lineNum)
+	}
+	return m
+}
+
+// guideIndent returns the indentation width of lineNum. Blank lines take
+// the smaller of their neighbours', so guides run through them unbroken.
+func (ui *UI) guideIndent(lineNum int) int {
+	lines := ui.buffer.Lines
+	if strings.TrimSpace(lines[lineNum]) != "" {
+		return indentColumns(lines[lineNum], ui.tabSize())
+	}
+
+	prev, next := 0, 0
+	for y := lineNum - 1; y >= max(lineNum-guideScan, 0); y-- {
+		if strings.TrimSpace(lines[y]) != "" {
+			prev = indentColumns(lines[y], ui.tabSize())
+			break
+		}
+	}
+	for y := lineNum + 1; y < min(lineNum+guideScan, len(lines)); y++ {
+		if strings.TrimSpace(lines[y]) != "" {
+			next = indentColumns(lines[y], ui.tabSize())
+			break
+		}
+	}
+	return min(prev, next)
+}
+
+func indentColumns(line string, tabSize int) int {
+	return buffer.ColumnAt(line, len(line)-len(strings.TrimLeft(line, " \t")), tabSize)
+}
+
+// isGuide reports whether an indentation guide is drawn at col.
+func (ui *UI) isGuide(col int, m lineMarks) bool {
+	return col < m.guides && col%ui.buffer.IndentWidth() == 0
+}
+
+// markRune returns the glyph drawn for whitespace rune r at offset and col
+// in place of the rune itself, if any.
+func (ui *UI) markRune(r rune, offset, col int, m lineMarks) (rune, bool) {
+	glyphs := ui.theme.Glyphs
+	if ui.config.Editor.ShowWhitespace {
+		switch {
+		case r == '\t':
+			return glyphs.Tab, true
+		case r == '\u00a0':
+			return glyphs.NBSP, true
+		case r == ' ' && offset >= m.trailing:
+			return glyphs.Space, true
+		}
+	}
+	if (r == ' ' || r == '\t') && offset < m.indent && ui.isGuide(col, m) {
+		return glyphs.Guide, true
+	}
+	return r, false
+}
+
+// drawGuides draws the rulers on the screen rows of a line and, if its
+// first row is shown, the indentation guides, which matter past the end of
+// a blank line. The line's text is drawn over them.
+func (ui *UI) drawGuides(screenY, rows int, firstRow bool, m lineMarks) {
+	gutter := ui.gutterWidth()
+	style := ui.whitespaceStyle(ui.textStyle())
+	glyph := ui.theme.Glyphs.Guide
+
+	// The buffer's own line length limit, from .editorconfig, is a ruler too
+	rulers := ui.config.Editor.Rulers
+	if n := ui.buffer.MaxLineLength; n > 0 {
+		rulers = append(rulers[:len(rulers):len(rulers)], n)
+	}
+	for _, ruler := range rulers {
+		x := gutter - ui.offsetX + ruler
+		if ruler <= 0 || x < gutter || x >= ui.width {
+			continue
+		}
+		for y := screenY; y < screenY+rows; y++ {
+			ui.screen.SetContent(x, y, glyph, nil, style)
+		}
+	}
+
+	for col := 0; firstRow && col < m.guides; col += ui.buffer.IndentWidth() {
+		if x := gutter - ui.offsetX + col; x >= gutter && x < ui.width {
+			ui.screen.SetContent(x, screenY, glyph, nil, style)
+		}
+	}
+}
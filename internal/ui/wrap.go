@@ -0,0 +1,272 @@
+package ui
+
+import (
+	"unicode/utf8"
+
+	"github.com/justynroberts/finpup/internal/buffer"
+	"github.com/justynroberts/finpup/internal/highlight"
+)
+
+// row is one screen row of a buffer line: the bytes [start, end) drawn
+// after indent blank columns. Without soft wrap every line is one row.
+type row struct {
+	start, end int
+	indent     int
+}
+
+// wrapLine splits line into rows of at most width columns, breaking after
+// a space where there is one. Continuation rows are indented like the line
+// itself, up to half the width, so wrapped code keeps its shape. A line
+// that fills its last row gets an empty row for the cursor at its end.
+// Tabs reach the next multiple of tabSize counted from the line's start.
+func wrapLine(line string, width, tabSize int) []row {
+	if width <= 0 || buffer.LineWidth(line, tabSize) < width {
+		return []row{{start: 0, end: len(line)}}
+	}
+
+	lead := len(line) - len(trimIndent(line))
+	indent := min(buffer.ColumnAt(line, lead, tabSize), width/2)
+
+	var rows []row
+	current := row{}
+	avail := width
+	// col is the column within the row, lineCol within the whole line
+	col, lineCol := 0, 0
+	// breakAt is the offset just after the last space in the current row
+	breakAt := -1
+	for i, r := range line {
+		w := buffer.CellWidth(r, lineCol, tabSize)
+		for col+w > avail && i > current.start {
+			end := i
+			if breakAt > current.start {
+				end = breakAt
+			}
+			current.end = end
+			rows = append(rows, current)
+
+			current = row{start: end, indent: indent}
+			avail = width - indent
+			col = lineCol - buffer.ColumnAt(line, end, tabSize)
+			breakAt = -1
+		}
+		col += w
+		lineCol += w
+		if (r == ' ' || r == '\t') && i >= lead {
+			breakAt = i + utf8.RuneLen(r)
+		}
+	}
+	current.end = len(line)
+	rows = append(rows, current)
+	if col >= avail {
+		rows = append(rows, row{start: len(line), end: len(line), indent: indent})
+	}
+	return rows
+}
+
+func trimIndent(line string) string {
+	for i, r := range line {
+		if r != ' ' && r != '\t' {
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+// rowAt returns the index of the row holding byte offset x. An offset on a
+// row boundary belongs to the later row.
+func rowAt(rows []row, x int) int {
+	for i := len(rows) - 1; i > 0; i-- {
+		if x >= rows[i].start {
+			return i
+		}
+	}
+	return 0
+}
+
+// lastRuneStart returns the offset of the rune ending at end, so a cursor
+// clamped to a wrapped row stays on that row.
+func lastRuneStart(line string, start, end int) int {
+	if end <= start {
+		return start
+	}
+	_, size := utf8.DecodeLastRuneInString(line[:end])
+	return end - size
+}
+
+// rows lays out buffer line lineNum for the current width.
+func (ui *UI) rows(lineNum int) []row {
+	line := ui.buffer.Lines[lineNum]
+	if !ui.wrap {
+		return []row{{start: 0, end: len(line)}}
+	}
+	return wrapLine(line, ui.width-ui.gutterWidth(), ui.tabSize())
+}
+
+// moveRows returns the row delta screen rows away from row r of line y,
+// stopping at either end of the buffer.
+func (ui *UI) moveRows(y, r, delta int) (int, int) {
+	for delta < 0 {
+		if r > 0 {
+			step := min(r, -delta)
+			r -= step
+			delta += step
+			continue
+		}
+		prev := ui.folds.Prev(y)
+		if prev < 0 {
+			break
+		}
+		y = prev
+		r = len(ui.rows(y)) - 1
+		delta++
+	}
+	for delta > 0 {
+		if n := len(ui.rows(y)); r < n-1 {
+			step := min(n-1-r, delta)
+			r += step
+			delta -= step
+			continue
+		}
+		next := ui.folds.Next(y)
+		if next >= len(ui.buffer.Lines) {
+			break
+		}
+		y = next
+		r = 0
+		delta--
+	}
+	return y, r
+}
+
+// rowsBetween counts the screen rows from (y1, r1) down to (y2, r2),
+// giving up once the count reaches limit.
+func (ui *UI) rowsBetween(y1, r1, y2, r2, limit int) int {
+	if y1 == y2 {
+		return r2 - r1
+	}
+	n := len(ui.rows(y1)) - r1
+	for y := ui.folds.Next(y1); y < y2 && n < limit; y = ui.folds.Next(y) {
+		n += len(ui.rows(y))
+	}
+	return n + r2
+}
+
+// before reports whether row r1 of line y1 comes before row r2 of line y2.
+func before(y1, r1, y2, r2 int) bool {
+	return y1 < y2 || (y1 == y2 && r1 < r2)
+}
+
+// scrollToCursor moves the view the least distance that shows the
+// cursor's row.
+func (ui *UI) scrollToCursor(contentHeight int) {
+	ui.offsetY = ui.VisibleLine(min(ui.offsetY, len(ui.buffer.Lines)-1))
+	ui.offsetRow = min(ui.offsetRow, len(ui.rows(ui.offsetY))-1)
+
+	y := ui.buffer.CursorY
+	r := rowAt(ui.rows(y), ui.buffer.CursorX)
+	if before(y, r, ui.offsetY, ui.offsetRow) {
+		ui.offsetY, ui.offsetRow = y, r
+		return
+	}
+	if ui.rowsBetween(ui.offsetY, ui.offsetRow, y, r, contentHeight) >= contentHeight {
+		ui.offsetY, ui.offsetRow = ui.moveRows(y, r, -(contentHeight - 1))
+	}
+}
+
+// scrollToColumn scrolls sideways so display column col of an unwrapped
+// line is on screen, keeping the configured margin of columns to either
+// side of it where the width allows.
+func (ui *UI) scrollToColumn(col int) {
+	if ui.wrap {
+		ui.offsetX = 0
+		return
+	}
+
+	width := ui.width - ui.gutterWidth()
+	margin := min(max(ui.config.Editor.SideScrollMargin, 0), (width-1)/2)
+	if col < ui.offsetX+margin {
+		ui.offsetX = max(col-margin, 0)
+	} else if col >= ui.offsetX+width-margin {
+		ui.offsetX = col - width + margin + 1
+	}
+}
+
+// ScrollHorizontal moves the view delta columns sideways without moving
+// the cursor. Wrapped lines never need it.
+func (ui *UI) ScrollHorizontal(delta int) {
+	if ui.wrap {
+		return
+	}
+	ui.offsetX = max(ui.offsetX+delta, 0)
+	ui.freeScroll = true
+}
+
+// cellAt returns the screen cell showing display column col of line y,
+// and whether it is on screen.
+func (ui *UI) cellAt(y, col, contentHeight int) (int, int, bool) {
+	line := ui.buffer.Lines[y]
+	rows := ui.rows(y)
+	r := rowAt(rows, buffer.OffsetAtColumn(line, col, ui.tabSize()))
+	if _, hidden := ui.folds.Hidden(y); hidden || before(y, r, ui.offsetY, ui.offsetRow) {
+		return 0, 0, false
+	}
+
+	screenY := ui.rowsBetween(ui.offsetY, ui.offsetRow, y, r, contentHeight)
+	gutter := ui.gutterWidth()
+	x := gutter + rows[r].indent + col - buffer.ColumnAt(line, rows[r].start, ui.tabSize()) - ui.offsetX
+	return x, screenY, screenY < contentHeight && x >= gutter && x < ui.width
+}
+
+// VisualMove returns the position dy screen rows from byte offset x of
+// line y, keeping the cursor's screen column. With soft wrap off a row is
+// a line.
+func (ui *UI) VisualMove(x, y, dy int) (int, int) {
+	line := ui.buffer.Lines[y]
+	rows := ui.rows(y)
+	r := rowAt(rows, x)
+	col := rows[r].indent + buffer.ColumnAt(line, x, ui.tabSize()) - buffer.ColumnAt(line, rows[r].start, ui.tabSize())
+
+	ny, nr := ui.moveRows(y, r, dy)
+	if ny == y && nr == r {
+		return x, y
+	}
+
+	line = ui.buffer.Lines[ny]
+	rows = ui.rows(ny)
+	target := rows[nr]
+	nx := buffer.OffsetAtColumn(line, buffer.ColumnAt(line, target.start, ui.tabSize())+max(col-target.indent, 0), ui.tabSize())
+	if nr < len(rows)-1 && nx >= target.end {
+		nx = lastRuneStart(line, target.start, target.end)
+	}
+	return nx, ny
+}
+
+// SoftWrap reports whether long lines are wrapped.
+func (ui *UI) SoftWrap() bool {
+	return ui.wrap
+}
+
+// ToggleSoftWrap switches wrapping for this buffer, overriding the
+// language default, and reports the new state.
+func (ui *UI) ToggleSoftWrap() bool {
+	ui.wrap = !ui.wrap
+	ui.wrapSet = true
+	ui.offsetRow = 0
+	ui.offsetX = 0
+	return ui.wrap
+}
+
+// applyWrapDefault picks soft wrap from the config for the current
+// language, unless it was toggled by hand.
+func (ui *UI) applyWrapDefault() {
+	if ui.wrapSet {
+		return
+	}
+	ui.wrap = ui.config.Editor.SoftWrap
+	for _, name := range ui.config.Editor.WrapLanguages {
+		if ui.language != "" && highlight.LanguageName(name) == ui.language {
+			ui.wrap = true
+		}
+	}
+	ui.offsetRow = 0
+}
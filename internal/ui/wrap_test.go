@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func rowText(line string, rows []row) []string {
+	var parts []string
+	for _, r := range rows {
+		parts = append(parts, strings.Repeat(">", r.indent)+line[r.start:r.end])
+	}
+	return parts
+}
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{"fits", "short line", 20, []string{"short line"}},
+		{"word boundary", "the quick brown fox", 10, []string{"the quick ", "brown fox"}},
+		{"long word", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"continuation indent", "    one two three four", 12, []string{"    one two ", ">>>>three ", ">>>>four"}},
+		{"indent capped at half", "        aaaa bbbb", 10, []string{"        aa", ">>>>>aa ", ">>>>>bbbb"}},
+		{"full last row", "abcdef", 3, []string{"abc", "def", ""}},
+		{"wide runes", "日本語テキスト", 6, []string{"日本語", "テキス", "ト"}},
+		{"tab stops", "a\tbc\tdefgh", 8, []string{"a\tbc\t", "defgh"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rowText(tt.line, wrapLine(tt.line, tt.width, 4))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSoftWrapDraw(t *testing.T) {
+	long := strings.Repeat("word ", 40) // 200 columns
+	ui := newTestUI(t, "notes.md", long+"\nnext\n")
+	if !ui.SoftWrap() {
+		t.Fatal("Expected Markdown to wrap by default")
+	}
+	ui.Draw()
+
+	// 96 text columns fit 19 words a row: three rows, numbered once
+	if mainc, _, _, _ := ui.screen.GetContent(2, 0); mainc != '1' {
+		t.Errorf("Expected line number 1 on the first row, got %q", mainc)
+	}
+	if mainc, _, _, _ := ui.screen.GetContent(2, 1); mainc != ' ' {
+		t.Errorf("Expected a blank gutter on a continuation row, got %q", mainc)
+	}
+	if mainc, _, _, _ := ui.screen.GetContent(2, 3); mainc != '2' {
+		t.Errorf("Expected line 2 on the fourth row, got %q", mainc)
+	}
+
+	// Down walks the rows of the wrapped line before leaving it
+	x, y := ui.VisualMove(5, 0, 1)
+	if y != 0 || x != 95+5 {
+		t.Errorf("Expected to stay on line 0 at 100, got (%d, %d)", x, y)
+	}
+	x, y = ui.VisualMove(x, y, 2)
+	if y != 1 || x != 4 {
+		t.Errorf("Expected the end of line 1, got (%d, %d)", x, y)
+	}
+
+	// A click on a continuation row maps into the middle of the line
+	if bx, by, _ := ui.ScreenToBuffer(ui.gutterWidth()+3, 1); bx != 95+3 || by != 0 {
+		t.Errorf("Expected (98, 0), got (%d, %d)", bx, by)
+	}
+
+	if ui.ToggleSoftWrap() {
+		t.Error("Expected the toggle to turn wrapping off")
+	}
+	ui.Draw()
+	if mainc, _, _, _ := ui.screen.GetContent(2, 1); mainc != '2' {
+		t.Errorf("Expected line 2 on the second row without wrap, got %q", mainc)
+	}
+}
+
+func TestSoftWrapKeepsCursorVisible(t *testing.T) {
+	long := strings.Repeat("x", 96*50) // 50 full rows and one for the cursor
+	ui := newTestUI(t, "notes.md", long)
+	ui.buffer.CursorX = len(long)
+	ui.Draw()
+
+	x, y, ok := ui.cellAt(0, len(long), 38)
+	if !ok || y != 37 || x != ui.gutterWidth() {
+		t.Errorf("Expected the cursor on the last text row, got (%d, %d, %v)", x, y, ok)
+	}
+}
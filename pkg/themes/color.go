@@ -0,0 +1,132 @@
+package themes
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// ColorDepth is how many colours the terminal can show.
+type ColorDepth int
+
+const (
+	// DepthMono shows no colours; contrast comes from text attributes.
+	DepthMono ColorDepth = iota
+	Depth16
+	Depth256
+	DepthTrueColor
+)
+
+func (d ColorDepth) String() string {
+	switch d {
+	case DepthMono:
+		return "mono"
+	case Depth16:
+		return "16"
+	case Depth256:
+		return "256"
+	}
+	return "truecolor"
+}
+
+// ParseColorDepth parses a color_mode config value. It returns false for
+// "auto", an empty string or anything unrecognised.
+func ParseColorDepth(s string) (ColorDepth, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "truecolor", "24bit":
+		return DepthTrueColor, true
+	case "256":
+		return Depth256, true
+	case "16", "8":
+		return Depth16, true
+	case "mono", "monochrome":
+		return DepthMono, true
+	}
+	return DepthTrueColor, false
+}
+
+// DetectColorDepth works out the colour depth from the environment and
+// the number of colours terminfo reports. COLORTERM wins over terminfo,
+// since many terminals support 24-bit colour without advertising it, and
+// NO_COLOR forces monochrome.
+func DetectColorDepth(getenv func(string) string, terminfoColors int) ColorDepth {
+	if getenv("NO_COLOR") != "" {
+		return DepthMono
+	}
+	switch strings.ToLower(getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return DepthTrueColor
+	}
+
+	switch {
+	case terminfoColors >= 1<<24:
+		return DepthTrueColor
+	case terminfoColors >= 256 || strings.Contains(getenv("TERM"), "256color"):
+		return Depth256
+	case terminfoColors >= 8:
+		return Depth16
+	}
+	return DepthMono
+}
+
+var (
+	fitMu    sync.Mutex
+	fitCache = map[ColorDepth]map[tcell.Color]tcell.Color{}
+)
+
+// Map returns the closest colour the depth can show. Monochrome maps every
+// colour to the terminal default.
+func (d ColorDepth) Map(c tcell.Color) tcell.Color {
+	if d == DepthTrueColor || c == tcell.ColorDefault || !c.IsRGB() {
+		return c
+	}
+	if d == DepthMono {
+		return tcell.ColorDefault
+	}
+
+	fitMu.Lock()
+	defer fitMu.Unlock()
+
+	cache := fitCache[d]
+	if cache == nil {
+		cache = make(map[tcell.Color]tcell.Color)
+		fitCache[d] = cache
+	}
+	if fit, ok := cache[c]; ok {
+		return fit
+	}
+
+	size := 256
+	if d == Depth16 {
+		size = 16
+	}
+	palette := make([]tcell.Color, size)
+	for i := range palette {
+		palette[i] = tcell.PaletteColor(i)
+	}
+	fit := tcell.FindColor(c, palette)
+	cache[c] = fit
+	return fit
+}
+
+// WithDepth returns a copy of the theme with every colour mapped to the
+// closest one available at depth d.
+func (t Theme) WithDepth(d ColorDepth) Theme {
+	if d == DepthTrueColor {
+		return t
+	}
+
+	for _, f := range t.colorFields() {
+		*f.color = d.Map(*f.color)
+	}
+	t.WhitespaceFG = d.Map(t.WhitespaceFG)
+	if t.Syntax != nil {
+		syntax := make(map[string]tcell.Color, len(t.Syntax))
+		for name, c := range t.Syntax {
+			syntax[name] = d.Map(c)
+		}
+		t.Syntax = syntax
+	}
+	return t
+}
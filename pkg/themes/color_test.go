@@ -0,0 +1,67 @@
+package themes
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestDetectColorDepth(t *testing.T) {
+	tests := []struct {
+		env    map[string]string
+		colors int
+		want   ColorDepth
+	}{
+		{map[string]string{"COLORTERM": "truecolor"}, 256, DepthTrueColor},
+		{map[string]string{"TERM": "xterm-256color"}, 8, Depth256},
+		{map[string]string{"TERM": "screen"}, 256, Depth256},
+		{map[string]string{"TERM": "xterm"}, 8, Depth16},
+		{map[string]string{"TERM": "vt100"}, 0, DepthMono},
+		{map[string]string{"COLORTERM": "truecolor", "NO_COLOR": "1"}, 256, DepthMono},
+	}
+
+	for _, tt := range tests {
+		getenv := func(key string) string { return tt.env[key] }
+		if got := DetectColorDepth(getenv, tt.colors); got != tt.want {
+			t.Errorf("DetectColorDepth(%v, %d) = %v, want %v", tt.env, tt.colors, got, tt.want)
+		}
+	}
+}
+
+func TestParseColorDepth(t *testing.T) {
+	if d, ok := ParseColorDepth("256"); !ok || d != Depth256 {
+		t.Errorf("Expected 256, got %v %v", d, ok)
+	}
+	if _, ok := ParseColorDepth("auto"); ok {
+		t.Error("auto should defer to detection")
+	}
+}
+
+func TestColorDepthMap(t *testing.T) {
+	red := tcell.NewRGBColor(250, 5, 5)
+	if got := Depth16.Map(red); got != tcell.ColorRed {
+		t.Errorf("Expected red to map to palette red, got %v", got)
+	}
+	if got := Depth256.Map(red); got.IsRGB() || got == tcell.ColorDefault {
+		t.Errorf("Expected a palette colour, got %v", got)
+	}
+	if got := DepthMono.Map(red); got != tcell.ColorDefault {
+		t.Errorf("Expected default colour in mono, got %v", got)
+	}
+	if got := DepthTrueColor.Map(red); got != red {
+		t.Errorf("Expected truecolor to keep %v, got %v", red, got)
+	}
+}
+
+func TestWithDepth(t *testing.T) {
+	theme := Dark
+	theme.Syntax = map[string]tcell.Color{"function": tcell.NewRGBColor(1, 2, 3)}
+
+	mapped := theme.WithDepth(Depth16)
+	if mapped.Background.IsRGB() || mapped.Syntax["function"].IsRGB() {
+		t.Error("Expected every colour to be mapped to the palette")
+	}
+	if !theme.Syntax["function"].IsRGB() {
+		t.Error("WithDepth should not modify the original theme")
+	}
+}
@@ -0,0 +1,308 @@
+package themes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// SyntaxTokens lists the token categories a theme's syntax section may
+// colour. Anything not listed falls back to the theme foreground.
+var SyntaxTokens = []string{
+	"keyword", "type", "function", "builtin", "variable", "constant",
+	"string", "number", "comment", "operator", "punctuation", "tag",
+	"attribute", "preprocessor", "error",
+}
+
+// colorFields maps theme file keys to the colour fields they set.
+func (t *Theme) colorFields() []struct {
+	key   string
+	color *tcell.Color
+} {
+	return []struct {
+		key   string
+		color *tcell.Color
+	}{
+		{"background", &t.Background},
+		{"foreground", &t.Foreground},
+		{"status_bg", &t.StatusBG},
+		{"status_fg", &t.StatusFG},
+		{"line_number_fg", &t.LineNumFG},
+		{"selection_bg", &t.SelectionBG},
+		{"selection_fg", &t.SelectionFG},
+		{"keyword_fg", &t.KeywordFG},
+		{"string_fg", &t.StringFG},
+		{"comment_fg", &t.CommentFG},
+		{"number_fg", &t.NumberFG},
+	}
+}
+
+// UserDir is where user themes are loaded from.
+func UserDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "finpup", "themes")
+}
+
+// Register adds a theme to AllThemes, replacing a user theme of the same
+// name. Built-in themes cannot be replaced.
+func Register(theme Theme) error {
+	for i, t := range AllThemes {
+		if t.Name != theme.Name {
+			continue
+		}
+		if i < builtinCount {
+			return fmt.Errorf("theme %q clashes with a built-in theme", theme.Name)
+		}
+		AllThemes[i] = theme
+		return nil
+	}
+	AllThemes = append(AllThemes, theme)
+	return nil
+}
+
+// LoadDir loads and registers every *.yaml theme in dir. A missing
+// directory is not an error; a bad file is reported without stopping the
+// others from loading.
+func LoadDir(dir string) ([]Theme, []error) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	ymlPaths, _ := filepath.Glob(filepath.Join(dir, "*.yml"))
+	paths = append(paths, ymlPaths...)
+	sort.Strings(paths)
+
+	var loaded []Theme
+	var errs []error
+	for _, path := range paths {
+		theme, err := LoadFile(path)
+		if err == nil {
+			err = Register(theme)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		loaded = append(loaded, theme)
+	}
+	return loaded, errs
+}
+
+// LoadFile reads a theme from a YAML file.
+func LoadFile(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	theme, err := Parse(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return theme, nil
+}
+
+// Parse decodes a theme definition. Colours are "#rrggbb" or "#rgb". If
+// "base" names a built-in theme, unspecified colours are taken from it;
+// otherwise every colour must be given. Errors name the offending line.
+func Parse(data []byte) (Theme, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Theme{}, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return Theme{}, errors.New("theme file must be a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	var theme Theme
+	set := make(map[string]bool)
+
+	// Apply the base first so explicit keys override it
+	if base := lookup(root, "base"); base != nil {
+		found := false
+		for _, t := range AllThemes[:builtinCount] {
+			if t.Name == base.Value {
+				theme, found = t, true
+				break
+			}
+		}
+		if !found {
+			return Theme{}, fmt.Errorf("line %d: base: unknown built-in theme %q", base.Line, base.Value)
+		}
+		theme.Name = ""
+		theme.Syntax = nil
+		for _, f := range theme.colorFields() {
+			set[f.key] = true
+		}
+	}
+
+	fields := theme.colorFields()
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "base":
+		case "name":
+			theme.Name = strings.TrimSpace(value.Value)
+		case "syntax_style":
+			theme.SyntaxStyle = value.Value
+		case "syntax":
+			syntax, err := parseSyntax(value)
+			if err != nil {
+				return Theme{}, err
+			}
+			theme.Syntax = syntax
+		case "whitespace_fg":
+			color, err := ParseColor(value.Value)
+			if err != nil {
+				return Theme{}, fmt.Errorf("line %d: %s: %w", value.Line, key.Value, err)
+			}
+			theme.WhitespaceFG = color
+		case "glyphs":
+			if err := parseGlyphs(value, &theme.Glyphs); err != nil {
+				return Theme{}, err
+			}
+		default:
+			matched := false
+			for _, f := range fields {
+				if f.key != key.Value {
+					continue
+				}
+				color, err := ParseColor(value.Value)
+				if err != nil {
+					return Theme{}, fmt.Errorf("line %d: %s: %w", value.Line, key.Value, err)
+				}
+				*f.color = color
+				set[f.key] = true
+				matched = true
+			}
+			if !matched {
+				return Theme{}, fmt.Errorf("line %d: unknown key %q", key.Line, key.Value)
+			}
+		}
+	}
+
+	if theme.Name == "" {
+		return Theme{}, errors.New("missing required key \"name\"")
+	}
+	var missing []string
+	for _, f := range fields {
+		if !set[f.key] {
+			missing = append(missing, f.key)
+		}
+	}
+	if len(missing) > 0 {
+		return Theme{}, fmt.Errorf("missing colours %s (or set \"base\" to inherit them)", strings.Join(missing, ", "))
+	}
+	if theme.SyntaxStyle == "" {
+		theme.SyntaxStyle = Dark.SyntaxStyle
+	}
+	// Whitespace colours and glyphs are optional even without a base
+	if theme.WhitespaceFG == 0 {
+		theme.WhitespaceFG = theme.LineNumFG
+	}
+	if theme.Glyphs == (Glyphs{}) {
+		theme.Glyphs = DefaultGlyphs
+	}
+
+	return theme, nil
+}
+
+func parseSyntax(node *yaml.Node) (map[string]tcell.Color, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: syntax: expected a mapping of token to colour", node.Line)
+	}
+
+	syntax := make(map[string]tcell.Color)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if !isSyntaxToken(key.Value) {
+			return nil, fmt.Errorf("line %d: syntax: unknown token %q (known: %s)",
+				key.Line, key.Value, strings.Join(SyntaxTokens, ", "))
+		}
+		color, err := ParseColor(value.Value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: syntax.%s: %w", value.Line, key.Value, err)
+		}
+		syntax[key.Value] = color
+	}
+	return syntax, nil
+}
+
+// parseGlyphs sets the glyphs named in node, leaving the others as they
+// are.
+func parseGlyphs(node *yaml.Node, glyphs *Glyphs) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: glyphs: expected a mapping of tab, space, nbsp and guide", node.Line)
+	}
+	if *glyphs == (Glyphs{}) {
+		*glyphs = DefaultGlyphs
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		var glyph *rune
+		switch key.Value {
+		case "tab":
+			glyph = &glyphs.Tab
+		case "space":
+			glyph = &glyphs.Space
+		case "nbsp":
+			glyph = &glyphs.NBSP
+		case "guide":
+			glyph = &glyphs.Guide
+		default:
+			return fmt.Errorf("line %d: glyphs: unknown glyph %q (known: tab, space, nbsp, guide)", key.Line, key.Value)
+		}
+		runes := []rune(value.Value)
+		if len(runes) != 1 {
+			return fmt.Errorf("line %d: glyphs.%s: want a single character, got %q", value.Line, key.Value, value.Value)
+		}
+		*glyph = runes[0]
+	}
+	return nil
+}
+
+func isSyntaxToken(name string) bool {
+	for _, t := range SyntaxTokens {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// ParseColor parses "#rrggbb" or "#rgb" into an RGB colour.
+func ParseColor(s string) (tcell.Color, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 || !strings.HasPrefix(strings.TrimSpace(s), "#") {
+		return tcell.ColorDefault, fmt.Errorf("invalid colour %q (want #rrggbb)", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return tcell.ColorDefault, fmt.Errorf("invalid colour %q (want #rrggbb)", s)
+	}
+	return tcell.NewRGBColor(int32(v>>16), int32(v>>8&0xff), int32(v&0xff)), nil
+}
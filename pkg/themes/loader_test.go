@@ -0,0 +1,106 @@
+package themes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#1a2b3c")
+	if err != nil || c != tcell.NewRGBColor(0x1a, 0x2b, 0x3c) {
+		t.Errorf("Unexpected colour %v (%v)", c, err)
+	}
+	c, err = ParseColor("#fa0")
+	if err != nil || c != tcell.NewRGBColor(0xff, 0xaa, 0x00) {
+		t.Errorf("Unexpected short colour %v (%v)", c, err)
+	}
+	for _, bad := range []string{"1a2b3c", "#12345", "#gggggg", "red"} {
+		if _, err := ParseColor(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseWithBase(t *testing.T) {
+	theme, err := Parse([]byte("name: ocean\nbase: dark\nbackground: \"#101820\"\nsyntax:\n  function: \"#8fa1b3\"\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if theme.Name != "ocean" || theme.Background != tcell.NewRGBColor(0x10, 0x18, 0x20) {
+		t.Errorf("Unexpected theme %+v", theme)
+	}
+	if theme.Foreground != Dark.Foreground || theme.SyntaxStyle != Dark.SyntaxStyle {
+		t.Error("Expected unspecified fields to come from the base theme")
+	}
+	if theme.Syntax["function"] != tcell.NewRGBColor(0x8f, 0xa1, 0xb3) {
+		t.Errorf("Unexpected syntax colours %v", theme.Syntax)
+	}
+}
+
+func TestParseWhitespace(t *testing.T) {
+	theme, err := Parse([]byte("name: dots\nbase: light\nglyphs:\n  tab: \"»\"\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if theme.Glyphs.Tab != '»' || theme.Glyphs.Space != DefaultGlyphs.Space {
+		t.Errorf("Expected only the tab glyph to change, got %+v", theme.Glyphs)
+	}
+	if theme.WhitespaceFG != Light.WhitespaceFG {
+		t.Error("Expected the whitespace colour to come from the base theme")
+	}
+
+	if _, err := Parse([]byte("name: x\nbase: dark\nglyphs:\n  tab: \"->\"\n")); err == nil ||
+		!strings.Contains(err.Error(), "line 4: glyphs.tab: want a single character") {
+		t.Errorf("Unexpected error for a two-character glyph: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"base: dark\n", "missing required key \"name\""},
+		{"name: x\nbackground: \"#000000\"\n", "missing colours foreground"},
+		{"name: x\nbase: dark\nforeground: blue\n", "line 3: foreground: invalid colour"},
+		{"name: x\nbase: dark\nforground: \"#000000\"\n", "line 3: unknown key \"forground\""},
+		{"name: x\nbase: nope\n", "line 2: base: unknown built-in theme"},
+		{"name: x\nbase: dark\nsyntax:\n  keywords: \"#000000\"\n", "line 4: syntax: unknown token \"keywords\""},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestLoadDirRegistersThemes(t *testing.T) {
+	saved := AllThemes
+	defer func() { AllThemes = saved }()
+	AllThemes = append([]Theme(nil), saved[:builtinCount]...)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ocean.yaml"), []byte("name: ocean\nbase: dark\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "clash.yaml"), []byte("name: light\nbase: dark\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("name: [\n"), 0644)
+
+	loaded, errs := LoadDir(dir)
+	if len(loaded) != 1 || loaded[0].Name != "ocean" {
+		t.Errorf("Unexpected loaded themes %v", loaded)
+	}
+	if len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %v", errs)
+	}
+	if GetTheme("ocean").Name != "ocean" {
+		t.Error("Loaded theme should be available by name")
+	}
+	if NextTheme(Solarized.Name).Name != "ocean" {
+		t.Error("Loaded theme should join the theme cycle after the built-ins")
+	}
+}
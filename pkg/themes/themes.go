@@ -15,8 +15,28 @@ type Theme struct {
 	StringFG    tcell.Color
 	CommentFG   tcell.Color
 	NumberFG    tcell.Color
+	// WhitespaceFG colours visible whitespace, indentation guides and
+	// rulers; Glyphs are the characters drawn for them.
+	WhitespaceFG tcell.Color
+	Glyphs       Glyphs
+	// SyntaxStyle is the chroma style used for token colours.
+	SyntaxStyle string
+	// Syntax overrides colours per token category (see SyntaxTokens).
+	Syntax map[string]tcell.Color
 }
 
+// Glyphs are the characters drawn in place of whitespace and for guides.
+// Each should be one cell wide.
+type Glyphs struct {
+	Tab   rune
+	Space rune // trailing spaces
+	NBSP  rune // non-breaking spaces
+	Guide rune // indentation guides and rulers
+}
+
+// DefaultGlyphs are used by themes that do not choose their own.
+var DefaultGlyphs = Glyphs{Tab: '→', Space: '·', NBSP: '␣', Guide: '│'}
+
 var (
 	Dark = Theme{
 		Name:       "dark",
@@ -31,6 +51,9 @@ var (
 		StringFG:    tcell.NewRGBColor(152, 251, 152),
 		CommentFG:   tcell.NewRGBColor(140, 140, 140),
 		NumberFG:    tcell.NewRGBColor(255, 105, 180),
+		WhitespaceFG: tcell.NewRGBColor(60, 60, 60),
+		Glyphs:       DefaultGlyphs,
+		SyntaxStyle: "monokai",
 	}
 
 	Light = Theme{
@@ -46,6 +69,9 @@ var (
 		StringFG:    tcell.NewRGBColor(0, 128, 0),
 		CommentFG:   tcell.NewRGBColor(128, 128, 128),
 		NumberFG:    tcell.NewRGBColor(148, 0, 211),
+		WhitespaceFG: tcell.NewRGBColor(215, 215, 215),
+		Glyphs:       DefaultGlyphs,
+		SyntaxStyle: "github",
 	}
 
 	Monokai = Theme{
@@ -61,6 +87,9 @@ var (
 		StringFG:    tcell.NewRGBColor(230, 219, 116),
 		CommentFG:   tcell.NewRGBColor(117, 113, 94),
 		NumberFG:    tcell.NewRGBColor(174, 129, 255),
+		WhitespaceFG: tcell.NewRGBColor(73, 72, 62),
+		Glyphs:       DefaultGlyphs,
+		SyntaxStyle: "monokai",
 	}
 
 	Solarized = Theme{
@@ -76,11 +105,18 @@ var (
 		StringFG:    tcell.NewRGBColor(42, 161, 152),
 		CommentFG:   tcell.NewRGBColor(88, 110, 117),
 		NumberFG:    tcell.NewRGBColor(211, 54, 130),
+		WhitespaceFG: tcell.NewRGBColor(7, 74, 90),
+		Glyphs:       DefaultGlyphs,
+		SyntaxStyle: "solarized-dark",
 	}
 )
 
 var AllThemes = []Theme{Dark, Light, Monokai, Solarized}
 
+// builtinCount is the number of built-in themes at the front of AllThemes;
+// user themes are registered after them.
+var builtinCount = len(AllThemes)
+
 func GetTheme(name string) Theme {
 	for _, theme := range AllThemes {
 		if theme.Name == name {